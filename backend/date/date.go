@@ -1,6 +1,15 @@
 package date
 
-import "fmt"
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
 
 // `Date` represents a date, accurate to the day of a month of a year.
 type Date struct {
@@ -27,9 +36,15 @@ const (
 	InvalidMonth = "INVALID_MONTH"
 )
 
+// The range of years considered sensible by `ValidateStrict`.
+const (
+	MinSensibleYear = 1900
+	MaxSensibleYear = 2200
+)
+
 // MonthToStr converts a month into its string representation. The returned
-// string is the full month name. To get a short version of the name, take a
-// substring of the first 3 characters.
+// string is the full month name. For the short version of the name, use
+// `Date.ShortMonth`.
 func MonthToStr(m uint) string {
 	switch m {
 	case Jan:
@@ -40,6 +55,8 @@ func MonthToStr(m uint) string {
 		return "March"
 	case Apr:
 		return "April"
+	case May:
+		return "May"
 	case Jun:
 		return "June"
 	case Jul:
@@ -59,6 +76,34 @@ func MonthToStr(m uint) string {
 	}
 }
 
+// `StrToMonth` converts the name of a month into the month. Both the full name
+// and the 3-letter abbreviation are accepted, regardless of case. If the name
+// is not recognized, 0 (an invalid month) is returned.
+func StrToMonth(s string) uint {
+	s = strings.ToLower(s)
+	for m := uint(Jan); m <= Dec; m++ {
+		name := strings.ToLower(MonthToStr(m))
+		if s == name || s == name[:3] {
+			return m
+		}
+	}
+	return 0
+}
+
+// `Now` returns the current time. It is used to determine the current date and
+// may be replaced (for example, in tests) to control the clock.
+var Now = time.Now
+
+// `Today` returns the current date, as determined by `Now`.
+func Today() *Date {
+	t := Now()
+	return &Date{
+		Day:   uint(t.Day()),
+		Month: uint(t.Month()),
+		Year:  uint(t.Year()),
+	}
+}
+
 // `New` is used to compose a new Date. Always use this method to create date
 // instances so that all dates in the system are valid.
 func New(year, month, day uint) (*Date, error) {
@@ -75,9 +120,9 @@ func New(year, month, day uint) (*Date, error) {
 	return d, nil
 }
 
-// `isLeapYear` returns whether the given year is a leap year using the
+// `IsLeapYear` returns whether the given year is a leap year using the
 // algorithm based on divisibility rules.
-func isLeapYear(y uint) bool {
+func IsLeapYear(y uint) bool {
 	if y%400 == 0 {
 		return true
 	} else if y%100 == 0 {
@@ -89,6 +134,24 @@ func isLeapYear(y uint) bool {
 	}
 }
 
+// `DaysInMonth` returns the number of days in the month `month` of the year
+// `year`, taking leap years into account. It returns 0 for an invalid month.
+func DaysInMonth(year, month uint) uint {
+	switch month {
+	case Feb:
+		if IsLeapYear(year) {
+			return 29
+		}
+		return 28
+	case Jan, Mar, May, Jul, Aug, Oct, Dec:
+		return 31
+	case Apr, Jun, Sep, Nov:
+		return 30
+	default:
+		return 0
+	}
+}
+
 // `IsValid` returns whether the `Date`, `d`, is a valid date. It checks that
 // the month and day values are correct as well as whether the day value is
 // correct, based on the month and whether the year is a leap one.
@@ -108,33 +171,20 @@ func (d *Date) IsValid() error {
 		)
 	}
 	// checks to ensure that the day value is valid, based on the month
-	isLeap := isLeapYear(d.Year)
-	var ub uint
-	switch d.Month {
-	case Feb:
-		{
-			if isLeap {
-				if d.Day > 29 {
-					return fmt.Errorf(
-						"day (%d) greater than 29 in leap year (%d)",
-						d.Day, d.Year,
-					)
-				}
-			} else {
-				if d.Day > 28 {
-					return fmt.Errorf(
-						"day (%d) greater than 28 in non-leap year (%d)",
-						d.Day, d.Year,
-					)
-				}
+	ub := DaysInMonth(d.Year, d.Month)
+	if d.Day > ub {
+		if d.Month == Feb {
+			if IsLeapYear(d.Year) {
+				return fmt.Errorf(
+					"day (%d) greater than 29 in leap year (%d)",
+					d.Day, d.Year,
+				)
 			}
+			return fmt.Errorf(
+				"day (%d) greater than 28 in non-leap year (%d)",
+				d.Day, d.Year,
+			)
 		}
-	case Jan, Mar, May, Jul, Aug, Oct, Dec:
-		ub = 31
-	default:
-		ub = 30
-	}
-	if d.Day > ub {
 		return fmt.Errorf(
 			"expected day (%d) to be at most %d for month %s",
 			d.Day, ub, MonthToStr(d.Month),
@@ -143,25 +193,569 @@ func (d *Date) IsValid() error {
 	return nil
 }
 
-// `String` returns a string representation of the date. For example, the
-// `String` method of the date returned by New(1999, 12, 28) would return the
-// representation "28th December, 1999". The ordinal representation of the day
-// is also taken into consideration. So for the date returned by New(1999, 1,
-// 1), the string representation would be "1st January, 1999".
-func (d *Date) String() string {
+// `Ordinal` returns the ordinal representation of the day `day`, such as
+// "1st", "22nd" or "13th".
+func Ordinal(day uint) string {
 	var ordinalExt string
-	switch d.Day % 10 {
-	case 1:
+	switch {
+	case 11 <= day%100 && day%100 <= 13:
+		// the teens always take "th"
+		ordinalExt = "th"
+	case day%10 == 1:
 		ordinalExt = "st"
-	case 2:
+	case day%10 == 2:
 		ordinalExt = "nd"
-	case 3:
+	case day%10 == 3:
 		ordinalExt = "rd"
 	default:
 		ordinalExt = "th"
 	}
+	return fmt.Sprintf("%d%s", day, ordinalExt)
+}
+
+// `String` returns a string representation of the date. For example, the
+// `String` method of the date returned by New(1999, 12, 28) would return the
+// representation "28th December, 1999". The ordinal representation of the day
+// is also taken into consideration. So for the date returned by New(1999, 1,
+// 1), the string representation would be "1st January, 1999".
+func (d *Date) String() string {
 	return fmt.Sprintf(
-		"%d%s %s, %d",
-		d.Day, ordinalExt, MonthToStr(d.Month), d.Year,
+		"%s %s, %d",
+		Ordinal(d.Day), MonthToStr(d.Month), d.Year,
 	)
 }
+
+// `epochJDN` is the Julian day number of 1st January, 1970.
+const epochJDN = 2440588
+
+// `daysSinceEpoch` returns the number of days between 1st January, 1970 and
+// the date `d`. Dates before the epoch yield negative values. It uses the
+// well-known days-from-civil algorithm, which works on a calendar year that
+// starts in March so that the leap day falls at the end of the year.
+func (d *Date) daysSinceEpoch() int {
+	y, m, day := int(d.Year), int(d.Month), int(d.Day)
+	if m <= 2 {
+		y--
+	}
+	era := y / 400
+	if y < 0 {
+		era = (y - 399) / 400
+	}
+	yoe := y - era*400
+	mp := (m + 9) % 12
+	doy := (153*mp+2)/5 + day - 1
+	doe := yoe*365 + yoe/4 - yoe/100 + doy
+	return era*146097 + doe - 719468
+}
+
+// `fromDaysSinceEpoch` returns the date which is `days` days after 1st
+// January, 1970. It is the inverse of `daysSinceEpoch` (the civil-from-days
+// algorithm).
+func fromDaysSinceEpoch(days int) *Date {
+	z := days + 719468
+	era := z / 146097
+	if z < 0 {
+		era = (z - 146096) / 146097
+	}
+	doe := z - era*146097
+	yoe := (doe - doe/1460 + doe/36524 - doe/146096) / 365
+	y := yoe + era*400
+	doy := doe - (365*yoe + yoe/4 - yoe/100)
+	mp := (5*doy + 2) / 153
+	day := doy - (153*mp+2)/5 + 1
+	m := mp + 3
+	if m > 12 {
+		m -= 12
+	}
+	if m <= 2 {
+		y++
+	}
+	return &Date{Day: uint(day), Month: uint(m), Year: uint(y)}
+}
+
+// `JulianDayNumber` returns the Julian day number of the date `d`, i.e. the
+// number of days since the beginning of the Julian period (the JDN of 1st
+// January, 2000 is 2451545).
+func (d *Date) JulianDayNumber() int {
+	return d.daysSinceEpoch() + epochJDN
+}
+
+// `FromJulianDayNumber` returns the date with the Julian day number `jdn`. It
+// is the inverse of `JulianDayNumber` for dates from the year 0 onwards.
+func FromJulianDayNumber(jdn int) *Date {
+	return fromDaysSinceEpoch(jdn - epochJDN)
+}
+
+// `AddDays` returns the date `n` days after the date `d` (or before, if `n` is
+// negative). The date `d` itself is not modified.
+func (d *Date) AddDays(n int) *Date {
+	return FromJulianDayNumber(d.JulianDayNumber() + n)
+}
+
+// `DaysBetween` returns the number of days from `a` to `b`. The result is
+// negative if `b` comes before `a`.
+func DaysBetween(a, b *Date) int {
+	return b.JulianDayNumber() - a.JulianDayNumber()
+}
+
+// `HumanDuration` returns a human-readable representation of the number of
+// nights between `a` and `b`, such as "1 night" or "3 nights". Spans where `b`
+// does not come after `a` are reported as "0 nights".
+func HumanDuration(a, b *Date) string {
+	nights := DaysBetween(a, b)
+	if nights < 0 {
+		nights = 0
+	}
+	if nights == 1 {
+		return "1 night"
+	}
+	return fmt.Sprintf("%d nights", nights)
+}
+
+// `IsToday` returns whether the date `d` is the current date.
+func (d *Date) IsToday() bool {
+	return DaysBetween(Today(), d) == 0
+}
+
+// `IsPast` returns whether the date `d` comes before the current date.
+func (d *Date) IsPast() bool {
+	return DaysBetween(Today(), d) < 0
+}
+
+// `IsFuture` returns whether the date `d` comes after the current date.
+func (d *Date) IsFuture() bool {
+	return DaysBetween(Today(), d) > 0
+}
+
+// `Clone` returns a pointer to a copy of the date `d`, which can be modified
+// without affecting `d`.
+func (d *Date) Clone() *Date {
+	c := *d
+	return &c
+}
+
+// `ShortMonth` returns the 3-letter abbreviation of the month of the date `d`,
+// such as "Jan". If the month is not valid, "INV" is returned.
+func (d *Date) ShortMonth() string {
+	name := MonthToStr(d.Month)
+	if name == InvalidMonth {
+		return "INV"
+	}
+	return name[:3]
+}
+
+// `parseUint` parses `s` as a decimal number with between `minDigits` and
+// `maxDigits` digits.
+func parseUint(s string, minDigits, maxDigits int) (uint, error) {
+	if len(s) < minDigits || len(s) > maxDigits {
+		if minDigits == maxDigits {
+			return 0, fmt.Errorf("expected %d digits in '%s'", minDigits, s)
+		}
+		return 0, fmt.Errorf("expected %d to %d digits in '%s'", minDigits, maxDigits, s)
+	}
+	n, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number '%s'", s)
+	}
+	return uint(n), nil
+}
+
+// `Parse` parses a date in the "YYYY-MM-DD" layout. The month and day may
+// also be written without padding, as in "2021-5-3".
+func Parse(s string) (*Date, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("parse err: '%s' not in layout YYYY-MM-DD", s)
+	}
+	year, err := parseUint(parts[0], 4, 4)
+	if err != nil {
+		return nil, fmt.Errorf("parse err (year): %s", err.Error())
+	}
+	month, err := parseUint(parts[1], 1, 2)
+	if err != nil {
+		return nil, fmt.Errorf("parse err (month): %s", err.Error())
+	}
+	day, err := parseUint(parts[2], 1, 2)
+	if err != nil {
+		return nil, fmt.Errorf("parse err (day): %s", err.Error())
+	}
+	return New(year, month, day)
+}
+
+// `parseSlashed` parses a date in the "DD/MM/YYYY" layout.
+func parseSlashed(s string) (*Date, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("parse err: '%s' not in layout DD/MM/YYYY", s)
+	}
+	day, err := parseUint(parts[0], 2, 2)
+	if err != nil {
+		return nil, fmt.Errorf("parse err (day): %s", err.Error())
+	}
+	month, err := parseUint(parts[1], 2, 2)
+	if err != nil {
+		return nil, fmt.Errorf("parse err (month): %s", err.Error())
+	}
+	year, err := parseUint(parts[2], 4, 4)
+	if err != nil {
+		return nil, fmt.Errorf("parse err (year): %s", err.Error())
+	}
+	return New(year, month, day)
+}
+
+// `parseNamed` parses a date in the "DD Month YYYY" layout, where the month is
+// anything accepted by `StrToMonth`.
+func parseNamed(s string) (*Date, error) {
+	parts := strings.Fields(s)
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("parse err: '%s' not in layout DD Month YYYY", s)
+	}
+	day, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("parse err (day): invalid number '%s'", parts[0])
+	}
+	month := StrToMonth(parts[1])
+	if month == 0 {
+		return nil, fmt.Errorf("parse err (month): unrecognized month '%s'", parts[1])
+	}
+	year, err := parseUint(parts[2], 4, 4)
+	if err != nil {
+		return nil, fmt.Errorf("parse err (year): %s", err.Error())
+	}
+	return New(year, month, uint(day))
+}
+
+// `ParseAny` parses a date in any of the supported layouts: "YYYY-MM-DD",
+// "DD/MM/YYYY" and "DD Month YYYY". The first successful parse is returned,
+// otherwise the error contains the reason each layout was rejected.
+func ParseAny(s string) (*Date, error) {
+	parsers := []func(string) (*Date, error){Parse, parseSlashed, parseNamed}
+	reasons := make([]string, 0, len(parsers))
+	for _, parse := range parsers {
+		d, err := parse(s)
+		if err == nil {
+			return d, nil
+		}
+		reasons = append(reasons, err.Error())
+	}
+	return nil, fmt.Errorf("unparseable date '%s': %s", s, strings.Join(reasons, "; "))
+}
+
+// `DateRange` is a span of consecutive days, from `Start` to `End` (both
+// inclusive).
+type DateRange struct {
+	Start *Date `json:"start"`
+	End   *Date `json:"end"`
+}
+
+// `NewRange` returns the `DateRange` from `start` to `end`. An error is returned
+// if `end` comes before `start`.
+func NewRange(start, end *Date) (DateRange, error) {
+	if DaysBetween(start, end) < 0 {
+		return DateRange{}, fmt.Errorf(
+			"invalid range: end (%s) before start (%s)",
+			end, start,
+		)
+	}
+	return DateRange{Start: start, End: end}, nil
+}
+
+// `Nights` returns the number of nights spent over the range, i.e. the number
+// of days from `Start` to `End`. A range starting and ending on the same day is
+// 0 nights.
+func (r DateRange) Nights() int {
+	return DaysBetween(r.Start, r.End)
+}
+
+// `SplitByMonth` splits the range into consecutive sub-ranges, each of which
+// is contained in a single calendar month. A range within one month is
+// returned as is.
+func (r DateRange) SplitByMonth() []DateRange {
+	if r.Start.Year == r.End.Year && r.Start.Month == r.End.Month {
+		return []DateRange{r}
+	}
+	var ranges []DateRange
+	start := r.Start.Clone()
+	for DaysBetween(start, r.End) >= 0 {
+		end := &Date{
+			Day:   DaysInMonth(start.Year, start.Month),
+			Month: start.Month,
+			Year:  start.Year,
+		}
+		if DaysBetween(end, r.End) < 0 {
+			end = r.End.Clone()
+		}
+		ranges = append(ranges, DateRange{Start: start, End: end})
+		// first day of the following month
+		start = &Date{Day: 1, Month: end.Month%12 + 1, Year: end.Year}
+		if end.Month == Dec {
+			start.Year++
+		}
+	}
+	return ranges
+}
+
+// `StartOfMonth` returns the first day of the month of the date `d`.
+func (d *Date) StartOfMonth() *Date {
+	return &Date{Day: 1, Month: d.Month, Year: d.Year}
+}
+
+// `EndOfMonth` returns the last day of the month of the date `d`.
+func (d *Date) EndOfMonth() *Date {
+	return &Date{Day: DaysInMonth(d.Year, d.Month), Month: d.Month, Year: d.Year}
+}
+
+// `Compare` compares the date `d` with `o`, returning -1 if `d` comes before
+// `o`, 1 if it comes after and 0 if they are the same date.
+func (d *Date) Compare(o *Date) int {
+	switch {
+	case d.Year != o.Year:
+		if d.Year < o.Year {
+			return -1
+		}
+		return 1
+	case d.Month != o.Month:
+		if d.Month < o.Month {
+			return -1
+		}
+		return 1
+	case d.Day != o.Day:
+		if d.Day < o.Day {
+			return -1
+		}
+		return 1
+	default:
+		return 0
+	}
+}
+
+// `Sort` sorts `dates` in place, in ascending (chronological) order.
+func Sort(dates []*Date) {
+	sort.SliceStable(dates, func(i, j int) bool {
+		return dates[i].Compare(dates[j]) < 0
+	})
+}
+
+// `SortDesc` sorts `dates` in place, in descending order.
+func SortDesc(dates []*Date) {
+	sort.SliceStable(dates, func(i, j int) bool {
+		return dates[i].Compare(dates[j]) > 0
+	})
+}
+
+// `Weekday` returns the day of the week of the date `d`.
+func (d *Date) Weekday() time.Weekday {
+	// the Julian day number 0 fell on a Monday
+	return time.Weekday((d.JulianDayNumber() + 1) % 7)
+}
+
+// `WeekdayName` returns the name of the day of the week of the date `d`, such
+// as "Monday".
+func (d *Date) WeekdayName() string {
+	return d.Weekday().String()
+}
+
+// `Between` returns whether the date `d` is within the range from `start` to
+// `end`, both inclusive. If `end` comes before `start`, they are swapped.
+func (d *Date) Between(start, end *Date) bool {
+	if start.Compare(end) > 0 {
+		start, end = end, start
+	}
+	return d.Compare(start) >= 0 && d.Compare(end) <= 0
+}
+
+// `Key` returns the date packed into a single number, YYYYMMDD (i.e.
+// year*10000 + month*100 + day). Unlike a `*Date`, the key is comparable by
+// value, so it is suitable as a map key, and keys order the same as dates.
+func (d *Date) Key() uint32 {
+	return uint32(d.Year*10000 + d.Month*100 + d.Day)
+}
+
+// `FromKey` returns the date packed into the key `k` by `Key`.
+func FromKey(k uint32) *Date {
+	return &Date{
+		Day:   uint(k % 100),
+		Month: uint(k / 100 % 100),
+		Year:  uint(k / 10000),
+	}
+}
+
+// `englishMonths` are the month names used by `Format`.
+var englishMonths = func() [12]string {
+	var months [12]string
+	for m := uint(Jan); m <= Dec; m++ {
+		months[m-1] = MonthToStr(m)
+	}
+	return months
+}()
+
+// `Format` returns the date formatted according to `layout`, using the English
+// month names. See `FormatWith` for the layout syntax.
+func (d *Date) Format(layout string) string {
+	return d.FormatWith(layout, englishMonths)
+}
+
+// `FormatWith` returns the date formatted according to `layout`, in which the
+// following tokens are replaced:
+//
+//	YYYY  the year, zero-padded to 4 digits
+//	MM    the month, zero-padded to 2 digits
+//	DD    the day, zero-padded to 2 digits
+//	Month the name of the month, from `months`
+//	Mon   the first 3 letters of the name of the month
+//
+// Tokens are matched anywhere in the layout, even within words (the "Mon" of
+// "Monday" is a token). A backslash makes the character following it literal,
+// so the layout `\Monday DD` formats as "Monday 31", and `\\` as a backslash.
+//
+// `months` holds the names of the months, January first, allowing localized
+// names to be used. An invalid month is formatted as `InvalidMonth`.
+func (d *Date) FormatWith(layout string, months [12]string) string {
+	name := InvalidMonth
+	if Jan <= d.Month && d.Month <= Dec {
+		name = months[d.Month-1]
+	}
+	short := []rune(name)
+	if len(short) > 3 {
+		short = short[:3]
+	}
+	// longer tokens first, so that "Month" is not taken for "Mon"
+	tokens := [...]struct{ token, value string }{
+		{"YYYY", fmt.Sprintf("%04d", d.Year)},
+		{"Month", name},
+		{"Mon", string(short)},
+		{"MM", fmt.Sprintf("%02d", d.Month)},
+		{"DD", fmt.Sprintf("%02d", d.Day)},
+	}
+	var b strings.Builder
+	for i := 0; i < len(layout); {
+		if layout[i] == '\\' && i+1 < len(layout) {
+			// escaped - the following character is written as is
+			_, size := utf8.DecodeRuneInString(layout[i+1:])
+			b.WriteString(layout[i+1 : i+1+size])
+			i += 1 + size
+			continue
+		}
+		matched := false
+		for _, t := range tokens {
+			if strings.HasPrefix(layout[i:], t.token) {
+				b.WriteString(t.value)
+				i += len(t.token)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			b.WriteByte(layout[i])
+			i++
+		}
+	}
+	return b.String()
+}
+
+// `Hash` returns a stable hash of the date: the 64-bit FNV-1a hash of its
+// `Key`. Equal dates always have equal hashes.
+func (d *Date) Hash() uint64 {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], d.Key())
+	h := fnv.New64a()
+	h.Write(buf[:])
+	return h.Sum64()
+}
+
+// `MonthsBetween` returns the number of full months from `a` to `b`. A month
+// is full once the day of the month of `a` is reached again, so 15th January to
+// 14th February is 0 months and to 15th February is 1 month. The result is
+// negative if `b` comes before `a`.
+func MonthsBetween(a, b *Date) int {
+	if a.Compare(b) > 0 {
+		return -MonthsBetween(b, a)
+	}
+	months := int(b.Year-a.Year)*12 + int(b.Month) - int(a.Month)
+	if b.Day < a.Day {
+		months--
+	}
+	return months
+}
+
+// `YearsBetween` returns the number of full years from `a` to `b` (see
+// `MonthsBetween`). The result is negative if `b` comes before `a`.
+func YearsBetween(a, b *Date) int {
+	return MonthsBetween(a, b) / 12
+}
+
+// `ToInt` returns the date packed into a single, sortable integer: YYYYMMDD.
+// It is the `Key` of the date, as an `int`.
+func (d *Date) ToInt() int {
+	return int(d.Key())
+}
+
+// `FromInt` returns the date packed into `n` by `ToInt`. An error is returned
+// if `n` does not hold a valid date.
+func FromInt(n int) (*Date, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid packed date (%d): must be positive", n)
+	}
+	return New(uint(n/10000), uint(n/100%100), uint(n%100))
+}
+
+// `ValidateStrict` is like `IsValid`, but additionally rejects dates whose year
+// is outside the range from `MinSensibleYear` to `MaxSensibleYear` (inclusive)
+// as suspicious. It is intended for user-entered dates, for which such years
+// are most likely typos - `IsValid` remains permissive for historical data.
+func (d *Date) ValidateStrict() error {
+	if err := d.IsValid(); err != nil {
+		return err
+	}
+	if d.Year < MinSensibleYear || d.Year > MaxSensibleYear {
+		return fmt.Errorf(
+			"suspicious year (%d): expected year between %d and %d (inclusive)",
+			d.Year, MinSensibleYear, MaxSensibleYear,
+		)
+	}
+	return nil
+}
+
+// `monthDay` returns the month and day of the date packed as MMDD, ignoring
+// the year.
+func (d *Date) monthDay() uint {
+	return d.Month*100 + d.Day
+}
+
+// `SameMonthDay` returns whether the dates `d` and `o` fall on the same month
+// and day, regardless of their years (e.g. anniversaries).
+func (d *Date) SameMonthDay(o *Date) bool {
+	return d.monthDay() == o.monthDay()
+}
+
+// `InAnnualWindow` returns whether the month and day of the date `d` fall
+// within the recurring window from the month and day of `start` to those of
+// `end` (both inclusive), ignoring years. If `end` falls earlier in the year
+// than `start`, the window wraps around the end of the year - for example,
+// 20th December to 5th January.
+func (d *Date) InAnnualWindow(start, end *Date) bool {
+	md, s, e := d.monthDay(), start.monthDay(), end.monthDay()
+	if s <= e {
+		return s <= md && md <= e
+	}
+	return md >= s || md <= e
+}
+
+// `AddBusinessDays` returns the date `n` business days after the date `d` (or
+// before, if `n` is negative), skipping Saturdays and Sundays. For example, one
+// business day after a Friday is the following Monday. The date `d` itself is
+// not modified.
+func (d *Date) AddBusinessDays(n int) *Date {
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	jdn := d.JulianDayNumber()
+	for n > 0 {
+		jdn += step
+		if wd := FromJulianDayNumber(jdn).Weekday(); wd != time.Saturday && wd != time.Sunday {
+			n--
+		}
+	}
+	return FromJulianDayNumber(jdn)
+}
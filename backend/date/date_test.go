@@ -0,0 +1,567 @@
+package date
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// `mustNew` returns the date `New(year, month, day)`, failing the test if it is
+// invalid.
+func mustNew(t *testing.T, year, month, day uint) *Date {
+	t.Helper()
+	d, err := New(year, month, day)
+	if err != nil {
+		t.Fatalf("New(%d, %d, %d): %s", year, month, day, err.Error())
+	}
+	return d
+}
+
+// `setToday` makes `Today` return the date `year-month-day`, returning a
+// function which restores the clock.
+func setToday(year, month, day uint) func() {
+	prev := Now
+	Now = func() time.Time {
+		return time.Date(int(year), time.Month(month), int(day), 12, 0, 0, 0, time.UTC)
+	}
+	return func() { Now = prev }
+}
+
+func TestIsValid(t *testing.T) {
+	tests := []struct {
+		year, month, day uint
+		valid            bool
+	}{
+		{2021, 1, 31, true},
+		{2021, 4, 31, false},
+		{2021, 2, 28, true},
+		{2021, 2, 29, false},
+		{2024, 2, 29, true},
+		{1900, 2, 29, false},
+		{2000, 2, 29, true},
+		{2021, 0, 1, false},
+		{2021, 13, 1, false},
+		{2021, 5, 0, false},
+	}
+	for _, tt := range tests {
+		d := &Date{Day: tt.day, Month: tt.month, Year: tt.year}
+		if err := d.IsValid(); (err == nil) != tt.valid {
+			t.Errorf("%d-%d-%d: got error %v, want valid: %t", tt.year, tt.month, tt.day, err, tt.valid)
+		}
+	}
+}
+
+func TestIsLeapYear(t *testing.T) {
+	tests := []struct {
+		year uint
+		leap bool
+	}{
+		{2000, true},
+		{1900, false},
+		{2024, true},
+		{2023, false},
+	}
+	for _, tt := range tests {
+		if got := IsLeapYear(tt.year); got != tt.leap {
+			t.Errorf("IsLeapYear(%d): got %t, want %t", tt.year, got, tt.leap)
+		}
+	}
+}
+
+func TestHumanDuration(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b *Date
+		want string
+	}{
+		{"zero", mustNew(t, 2021, 3, 1), mustNew(t, 2021, 3, 1), "0 nights"},
+		{"one", mustNew(t, 2021, 3, 1), mustNew(t, 2021, 3, 2), "1 night"},
+		{"several", mustNew(t, 2021, 2, 27), mustNew(t, 2021, 3, 2), "3 nights"},
+		{"reversed", mustNew(t, 2021, 3, 2), mustNew(t, 2021, 3, 1), "0 nights"},
+	}
+	for _, tt := range tests {
+		if got := HumanDuration(tt.a, tt.b); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRelativeToToday(t *testing.T) {
+	defer setToday(2021, 6, 15)()
+	tests := []struct {
+		name                  string
+		d                     *Date
+		today, past, isFuture bool
+	}{
+		{"today", mustNew(t, 2021, 6, 15), true, false, false},
+		{"yesterday", mustNew(t, 2021, 6, 14), false, true, false},
+		{"tomorrow", mustNew(t, 2021, 6, 16), false, false, true},
+		{"last year", mustNew(t, 2020, 6, 15), false, true, false},
+		{"next year", mustNew(t, 2022, 1, 1), false, false, true},
+	}
+	for _, tt := range tests {
+		if got := tt.d.IsToday(); got != tt.today {
+			t.Errorf("%s: IsToday got %t", tt.name, got)
+		}
+		if got := tt.d.IsPast(); got != tt.past {
+			t.Errorf("%s: IsPast got %t", tt.name, got)
+		}
+		if got := tt.d.IsFuture(); got != tt.isFuture {
+			t.Errorf("%s: IsFuture got %t", tt.name, got)
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+	d := mustNew(t, 2021, 6, 15)
+	c := d.Clone()
+	c.Day = 20
+	if d.Day != 15 {
+		t.Errorf("modifying the clone changed the original (day %d)", d.Day)
+	}
+}
+
+func TestShortMonth(t *testing.T) {
+	tests := []struct {
+		month uint
+		want  string
+	}{
+		{Jan, "Jan"},
+		{May, "May"},
+		{Jun, "Jun"},
+		{Jul, "Jul"},
+		{Sep, "Sep"},
+		{Dec, "Dec"},
+		{0, "INV"},
+		{13, "INV"},
+	}
+	for _, tt := range tests {
+		d := &Date{Day: 1, Month: tt.month, Year: 2021}
+		if got := d.ShortMonth(); got != tt.want {
+			t.Errorf("month %d: got %q, want %q", tt.month, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		s    string
+		want *Date
+	}{
+		{"2021-05-03", &Date{Day: 3, Month: 5, Year: 2021}},
+		{"2021-5-3", &Date{Day: 3, Month: 5, Year: 2021}},
+		{"2021-05-3", &Date{Day: 3, Month: 5, Year: 2021}},
+		{"2021-5-03", &Date{Day: 3, Month: 5, Year: 2021}},
+		{"2021-12-31", &Date{Day: 31, Month: 12, Year: 2021}},
+		{"2024-2-29", &Date{Day: 29, Month: 2, Year: 2024}},
+		// rejected
+		{"2021-x-3", nil},
+		{"2021-5-y", nil},
+		{"2021-+5-3", nil},
+		{"2021--3", nil},
+		{"2021-005-03", nil},
+		{"21-05-03", nil},
+		{"2021-13-1", nil},
+		{"2021-2-29", nil},
+		{"2021-05", nil},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.s)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("Parse(%q): expected error, got %v", tt.s, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q): unexpected error %s", tt.s, err.Error())
+		} else if *got != *tt.want {
+			t.Errorf("Parse(%q): got %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestParseAny(t *testing.T) {
+	want := Date{Day: 7, Month: 3, Year: 2021}
+	for _, s := range []string{"2021-03-07", "07/03/2021", "7 March 2021", "07 mar 2021"} {
+		got, err := ParseAny(s)
+		if err != nil {
+			t.Errorf("ParseAny(%q): unexpected error %s", s, err.Error())
+		} else if *got != want {
+			t.Errorf("ParseAny(%q): got %v, want %v", s, got, want)
+		}
+	}
+	if got, err := ParseAny("next tuesday"); err == nil {
+		t.Errorf("ParseAny: expected error, got %v", got)
+	}
+}
+
+func TestSplitByMonth(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end *Date
+		want       [][2]*Date
+	}{
+		{
+			"single month",
+			mustNew(t, 2021, 3, 5), mustNew(t, 2021, 3, 20),
+			[][2]*Date{{mustNew(t, 2021, 3, 5), mustNew(t, 2021, 3, 20)}},
+		},
+		{
+			"three months",
+			mustNew(t, 2024, 1, 20), mustNew(t, 2024, 3, 10),
+			[][2]*Date{
+				{mustNew(t, 2024, 1, 20), mustNew(t, 2024, 1, 31)},
+				{mustNew(t, 2024, 2, 1), mustNew(t, 2024, 2, 29)},
+				{mustNew(t, 2024, 3, 1), mustNew(t, 2024, 3, 10)},
+			},
+		},
+		{
+			"across the year",
+			mustNew(t, 2021, 12, 30), mustNew(t, 2022, 1, 2),
+			[][2]*Date{
+				{mustNew(t, 2021, 12, 30), mustNew(t, 2021, 12, 31)},
+				{mustNew(t, 2022, 1, 1), mustNew(t, 2022, 1, 2)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		r, err := NewRange(tt.start, tt.end)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err.Error())
+		}
+		got := r.SplitByMonth()
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %d ranges, want %d", tt.name, len(got), len(tt.want))
+			continue
+		}
+		for i, sub := range got {
+			if *sub.Start != *tt.want[i][0] || *sub.End != *tt.want[i][1] {
+				t.Errorf("%s: range %d: got %v - %v, want %v - %v",
+					tt.name, i, sub.Start, sub.End, tt.want[i][0], tt.want[i][1])
+			}
+		}
+	}
+	if _, err := NewRange(mustNew(t, 2021, 3, 2), mustNew(t, 2021, 3, 1)); err == nil {
+		t.Errorf("NewRange: expected error for a reversed range")
+	}
+}
+
+func TestNights(t *testing.T) {
+	tests := []struct {
+		start, end *Date
+		want       int
+	}{
+		{mustNew(t, 2021, 3, 1), mustNew(t, 2021, 3, 1), 0},
+		{mustNew(t, 2021, 3, 1), mustNew(t, 2021, 3, 2), 1},
+		{mustNew(t, 2021, 2, 26), mustNew(t, 2021, 3, 3), 5},
+	}
+	for _, tt := range tests {
+		if got := (DateRange{Start: tt.start, End: tt.end}).Nights(); got != tt.want {
+			t.Errorf("%v - %v: got %d nights, want %d", tt.start, tt.end, got, tt.want)
+		}
+	}
+}
+
+func TestStartAndEndOfMonth(t *testing.T) {
+	tests := []struct {
+		d          *Date
+		start, end *Date
+	}{
+		{mustNew(t, 2024, 2, 10), mustNew(t, 2024, 2, 1), mustNew(t, 2024, 2, 29)},
+		{mustNew(t, 2023, 2, 10), mustNew(t, 2023, 2, 1), mustNew(t, 2023, 2, 28)},
+		{mustNew(t, 2023, 7, 31), mustNew(t, 2023, 7, 1), mustNew(t, 2023, 7, 31)},
+	}
+	for _, tt := range tests {
+		if got := tt.d.StartOfMonth(); *got != *tt.start {
+			t.Errorf("StartOfMonth(%v): got %v, want %v", tt.d, got, tt.start)
+		}
+		if got := tt.d.EndOfMonth(); *got != *tt.end {
+			t.Errorf("EndOfMonth(%v): got %v, want %v", tt.d, got, tt.end)
+		}
+	}
+}
+
+func TestJulianDayNumber(t *testing.T) {
+	known := []struct {
+		d   *Date
+		jdn int
+	}{
+		{mustNew(t, 2000, 1, 1), 2451545},
+		{mustNew(t, 1970, 1, 1), 2440588},
+		{mustNew(t, 1858, 11, 17), 2400001},
+	}
+	for _, tt := range known {
+		if got := tt.d.JulianDayNumber(); got != tt.jdn {
+			t.Errorf("JulianDayNumber(%v): got %d, want %d", tt.d, got, tt.jdn)
+		}
+	}
+	// round trip over every day of several years, including leap years
+	d := mustNew(t, 1899, 12, 1)
+	for jdn := d.JulianDayNumber(); jdn < 2460000; jdn++ {
+		got := FromJulianDayNumber(jdn)
+		if err := got.IsValid(); err != nil {
+			t.Fatalf("FromJulianDayNumber(%d): invalid date %v: %s", jdn, got, err.Error())
+		}
+		if back := got.JulianDayNumber(); back != jdn {
+			t.Fatalf("round trip of %d: got %d (%v)", jdn, back, got)
+		}
+	}
+	if got := mustNew(t, 2024, 2, 28).AddDays(2); *got != *mustNew(t, 2024, 3, 1) {
+		t.Errorf("AddDays: got %v", got)
+	}
+	if got := mustNew(t, 2021, 1, 1).AddDays(-1); *got != *mustNew(t, 2020, 12, 31) {
+		t.Errorf("AddDays: got %v", got)
+	}
+}
+
+func TestSort(t *testing.T) {
+	a, b, c := mustNew(t, 2020, 12, 31), mustNew(t, 2021, 1, 1), mustNew(t, 2021, 2, 1)
+	dup := b.Clone()
+	dates := []*Date{c, b, a, dup}
+	Sort(dates)
+	if want := []*Date{a, b, dup, c}; !reflect.DeepEqual(dates, want) {
+		t.Errorf("Sort: got %v, want %v", dates, want)
+	}
+	SortDesc(dates)
+	if want := []*Date{c, b, dup, a}; !reflect.DeepEqual(dates, want) {
+		t.Errorf("SortDesc: got %v, want %v", dates, want)
+	}
+	if a.Compare(b) != -1 || b.Compare(a) != 1 || b.Compare(dup) != 0 {
+		t.Errorf("Compare: inconsistent results")
+	}
+}
+
+func TestWeekdayName(t *testing.T) {
+	tests := []struct {
+		d    *Date
+		want string
+	}{
+		{mustNew(t, 2021, 1, 1), "Friday"},
+		{mustNew(t, 2000, 1, 1), "Saturday"},
+		{mustNew(t, 2024, 2, 29), "Thursday"},
+		{mustNew(t, 1970, 1, 4), "Sunday"},
+	}
+	for _, tt := range tests {
+		if got := tt.d.WeekdayName(); got != tt.want {
+			t.Errorf("WeekdayName(%v): got %s, want %s", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestBetween(t *testing.T) {
+	start, end := mustNew(t, 2021, 3, 1), mustNew(t, 2021, 3, 31)
+	tests := []struct {
+		d    *Date
+		want bool
+	}{
+		{mustNew(t, 2021, 3, 15), true},
+		{mustNew(t, 2021, 3, 1), true},
+		{mustNew(t, 2021, 3, 31), true},
+		{mustNew(t, 2021, 2, 28), false},
+		{mustNew(t, 2021, 4, 1), false},
+	}
+	for _, tt := range tests {
+		if got := tt.d.Between(start, end); got != tt.want {
+			t.Errorf("%v.Between: got %t, want %t", tt.d, got, tt.want)
+		}
+		if got := tt.d.Between(end, start); got != tt.want {
+			t.Errorf("%v.Between (swapped): got %t, want %t", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	dates := []*Date{
+		mustNew(t, 1999, 12, 31),
+		mustNew(t, 2000, 1, 1),
+		mustNew(t, 2000, 2, 29),
+		mustNew(t, 2021, 11, 5),
+	}
+	for i, d := range dates {
+		if got := FromKey(d.Key()); *got != *d {
+			t.Errorf("round trip of %v: got %v", d, got)
+		}
+		if got := d.ToInt(); got != int(d.Key()) {
+			t.Errorf("ToInt(%v): got %d, want the key %d", d, got, d.Key())
+		}
+		if i > 0 && dates[i-1].Key() >= d.Key() {
+			t.Errorf("key of %v not after key of %v", d, dates[i-1])
+		}
+	}
+	if got := mustNew(t, 2021, 11, 5).Key(); got != 20211105 {
+		t.Errorf("Key: got %d, want 20211105", got)
+	}
+}
+
+func TestFromInt(t *testing.T) {
+	d := mustNew(t, 2021, 11, 5)
+	if got, err := FromInt(d.ToInt()); err != nil || *got != *d {
+		t.Errorf("round trip of %v: got %v (err: %v)", d, got, err)
+	}
+	for _, n := range []int{0, -20210101, 20211301, 20210230, 20210100} {
+		if got, err := FromInt(n); err == nil {
+			t.Errorf("FromInt(%d): expected error, got %v", n, got)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	french := [12]string{
+		"janvier", "février", "mars", "avril", "mai", "juin",
+		"juillet", "août", "septembre", "octobre", "novembre", "décembre",
+	}
+	d := mustNew(t, 2021, 2, 3)
+	tests := []struct {
+		layout string
+		months [12]string
+		want   string
+	}{
+		{"YYYY-MM-DD", englishMonths, "2021-02-03"},
+		{"DD Month YYYY", englishMonths, "03 February 2021"},
+		{"Mon DD, YYYY", englishMonths, "Feb 03, 2021"},
+		{"DD Month YYYY", french, "03 février 2021"},
+		{"DD Mon", french, "03 fév"},
+		// tokens are matched within words, unless escaped
+		{"Monday", englishMonths, "Febday"},
+		{`\Monday DD Month`, englishMonths, "Monday 03 February"},
+		{`\MM is MM`, englishMonths, "MM is 02"},
+		{`a \\ b`, englishMonths, `a \ b`},
+		{`trailing \`, englishMonths, `trailing \`},
+	}
+	for _, tt := range tests {
+		if got := d.FormatWith(tt.layout, tt.months); got != tt.want {
+			t.Errorf("FormatWith(%q): got %q, want %q", tt.layout, got, tt.want)
+		}
+	}
+	if got := d.Format("DD Month"); got != "03 February" {
+		t.Errorf("Format: got %q", got)
+	}
+}
+
+func TestHash(t *testing.T) {
+	a, b := mustNew(t, 2021, 3, 1), mustNew(t, 2021, 3, 1)
+	if a.Hash() != b.Hash() {
+		t.Errorf("equal dates hash differently")
+	}
+	seen := make(map[uint64]*Date)
+	d := mustNew(t, 2021, 1, 1)
+	for i := 0; i < 1000; i++ {
+		if prev, ok := seen[d.Hash()]; ok {
+			t.Errorf("%v and %v hash equally", prev, d)
+		}
+		seen[d.Hash()] = d
+		d = d.AddDays(1)
+	}
+}
+
+func TestMonthsBetween(t *testing.T) {
+	tests := []struct {
+		a, b          *Date
+		months, years int
+	}{
+		{mustNew(t, 2021, 1, 15), mustNew(t, 2021, 2, 14), 0, 0},
+		{mustNew(t, 2021, 1, 15), mustNew(t, 2021, 2, 15), 1, 0},
+		{mustNew(t, 2020, 3, 10), mustNew(t, 2021, 3, 9), 11, 0},
+		{mustNew(t, 2020, 3, 10), mustNew(t, 2021, 3, 10), 12, 1},
+		{mustNew(t, 2021, 2, 15), mustNew(t, 2021, 1, 15), -1, 0},
+		{mustNew(t, 2023, 3, 10), mustNew(t, 2020, 3, 10), -36, -3},
+	}
+	for _, tt := range tests {
+		if got := MonthsBetween(tt.a, tt.b); got != tt.months {
+			t.Errorf("MonthsBetween(%v, %v): got %d, want %d", tt.a, tt.b, got, tt.months)
+		}
+		if got := YearsBetween(tt.a, tt.b); got != tt.years {
+			t.Errorf("YearsBetween(%v, %v): got %d, want %d", tt.a, tt.b, got, tt.years)
+		}
+	}
+}
+
+func TestValidateStrict(t *testing.T) {
+	tests := []struct {
+		year  uint
+		valid bool
+	}{
+		{0, false},
+		{1850, false},
+		{2021, true},
+		{3000, false},
+	}
+	for _, tt := range tests {
+		d := &Date{Day: 1, Month: 1, Year: tt.year}
+		if err := d.ValidateStrict(); (err == nil) != tt.valid {
+			t.Errorf("year %d: got error %v, want valid: %t", tt.year, err, tt.valid)
+		}
+		if err := d.IsValid(); err != nil {
+			t.Errorf("year %d: IsValid should be permissive, got %s", tt.year, err.Error())
+		}
+	}
+}
+
+func TestAnnualWindow(t *testing.T) {
+	if !mustNew(t, 2019, 7, 4).SameMonthDay(mustNew(t, 2021, 7, 4)) {
+		t.Errorf("SameMonthDay: same month and day in different years")
+	}
+	if mustNew(t, 2021, 7, 4).SameMonthDay(mustNew(t, 2021, 7, 5)) {
+		t.Errorf("SameMonthDay: different days")
+	}
+	start, end := mustNew(t, 2000, 12, 20), mustNew(t, 2000, 1, 5)
+	tests := []struct {
+		d    *Date
+		want bool
+	}{
+		{mustNew(t, 2021, 12, 20), true},
+		{mustNew(t, 2021, 12, 31), true},
+		{mustNew(t, 2022, 1, 1), true},
+		{mustNew(t, 2022, 1, 5), true},
+		{mustNew(t, 2022, 1, 6), false},
+		{mustNew(t, 2021, 12, 19), false},
+		{mustNew(t, 2021, 6, 1), false},
+	}
+	for _, tt := range tests {
+		if got := tt.d.InAnnualWindow(start, end); got != tt.want {
+			t.Errorf("%v in wrapping window: got %t, want %t", tt.d, got, tt.want)
+		}
+	}
+	if !mustNew(t, 2021, 6, 1).InAnnualWindow(mustNew(t, 1, 5, 1), mustNew(t, 1, 6, 30)) {
+		t.Errorf("date in a non-wrapping window not matched")
+	}
+}
+
+func TestOrdinal(t *testing.T) {
+	tests := map[uint]string{
+		1: "1st", 2: "2nd", 3: "3rd", 4: "4th",
+		11: "11th", 12: "12th", 13: "13th",
+		21: "21st", 22: "22nd", 23: "23rd", 31: "31st",
+	}
+	for day, want := range tests {
+		if got := Ordinal(day); got != want {
+			t.Errorf("Ordinal(%d): got %q, want %q", day, got, want)
+		}
+	}
+	if got := mustNew(t, 1999, 12, 12).String(); got != "12th December, 1999" {
+		t.Errorf("String: got %q", got)
+	}
+}
+
+func TestAddBusinessDays(t *testing.T) {
+	friday := mustNew(t, 2021, 1, 8)
+	tests := []struct {
+		name string
+		d    *Date
+		n    int
+		want *Date
+	}{
+		{"zero", friday, 0, friday},
+		{"friday to monday", friday, 1, mustNew(t, 2021, 1, 11)},
+		{"across a weekend", mustNew(t, 2021, 1, 7), 3, mustNew(t, 2021, 1, 12)},
+		{"two weeks", friday, 10, mustNew(t, 2021, 1, 22)},
+		{"backwards across a weekend", mustNew(t, 2021, 1, 11), -1, friday},
+		{"backwards from a sunday", mustNew(t, 2021, 1, 10), -2, mustNew(t, 2021, 1, 7)},
+	}
+	for _, tt := range tests {
+		if got := tt.d.AddBusinessDays(tt.n); *got != *tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
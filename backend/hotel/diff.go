@@ -0,0 +1,65 @@
+package hotel
+
+import (
+	"github.com/navaz-alani/hotel/room"
+)
+
+// `HotelDiff` describes the differences between two snapshots of a `Hotel`.
+type HotelDiff struct {
+	// `Added` contains the numbers of the rooms which are only in the later
+	// snapshot, in ascending order.
+	Added []room.Number
+	// `Removed` contains the numbers of the rooms which are only in the earlier
+	// snapshot, in ascending order.
+	Removed []room.Number
+	// `Changed` maps the number of each room present in both snapshots, whose
+	// fields differ, to the names of the changed fields (`room.HeaderPrice`,
+	// `room.HeaderState` and/or `room.HeaderAttributes`).
+	Changed map[room.Number][]string
+}
+
+// `Diff` returns the differences between the hotels `before` and `after`, in
+// which soft deleted rooms count as absent. The locks of the two hotels are
+// never held together, so concurrent calls cannot deadlock, even with the
+// hotels swapped.
+func Diff(before, after *Hotel) HotelDiff {
+	diff := HotelDiff{Changed: make(map[room.Number][]string)}
+	if before == after {
+		return diff
+	}
+	// diff against a snapshot of `before`, so that the locks of both hotels
+	// are never held together (see `room.Room.Equal`)
+	before = before.Clone()
+	after.mu.RLock()
+	defer after.mu.RUnlock()
+
+	for _, num := range before.sortedNumbers() {
+		b := before.rooms[num]
+		a, ok := after.liveRoom(num)
+		if !ok {
+			diff.Removed = append(diff.Removed, num)
+			continue
+		}
+		var changed []string
+		if b.Price() != a.Price() {
+			changed = append(changed, room.HeaderPrice)
+		}
+		if b.State() != a.State() {
+			changed = append(changed, room.HeaderState)
+		}
+		if !room.NewAttributeSet(b.Attributes()...).Equal(
+			room.NewAttributeSet(a.Attributes()...),
+		) {
+			changed = append(changed, room.HeaderAttributes)
+		}
+		if len(changed) > 0 {
+			diff.Changed[num] = changed
+		}
+	}
+	for _, num := range after.sortedNumbers() {
+		if _, ok := before.liveRoom(num); !ok {
+			diff.Added = append(diff.Added, num)
+		}
+	}
+	return diff
+}
@@ -0,0 +1,91 @@
+package hotel
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/navaz-alani/hotel/room"
+)
+
+func TestDiff(t *testing.T) {
+	before := newTestHotel(t, testAttrData, testRoomData)
+	after := newTestHotel(t, testAttrData, `room_number,price,state,attributes
+101,110,FREE,"balcony,sea_view"
+102,80,OCCUPIED,balcony
+103,120,OCCUPIED,"minibar,balcony"
+105,70,FREE,
+`)
+	tests := []struct {
+		name          string
+		before, after *Hotel
+		want          HotelDiff
+	}{
+		{
+			"changed",
+			before, after,
+			HotelDiff{
+				Added:   []room.Number{105},
+				Removed: []room.Number{104},
+				Changed: map[room.Number][]string{
+					101: {room.HeaderPrice},
+					102: {room.HeaderState},
+					103: {room.HeaderAttributes},
+				},
+			},
+		},
+		{
+			"swapped",
+			after, before,
+			HotelDiff{
+				Added:   []room.Number{104},
+				Removed: []room.Number{105},
+				Changed: map[room.Number][]string{
+					101: {room.HeaderPrice},
+					102: {room.HeaderState},
+					103: {room.HeaderAttributes},
+				},
+			},
+		},
+		{"same hotel", before, before, HotelDiff{Changed: map[room.Number][]string{}}},
+		{"clone", before, before.Clone(), HotelDiff{Changed: map[room.Number][]string{}}},
+	}
+	for _, tt := range tests {
+		if got := Diff(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDiffSoftDeleted(t *testing.T) {
+	h := newTestHotel(t, testAttrData, testRoomData)
+	deleted := h.Clone()
+	deleted.SoftDeleteRoom(102)
+	bothDeleted := deleted.Clone()
+	bothDeleted.Rooms()[0].SetPrice(110)
+	tests := []struct {
+		name          string
+		before, after *Hotel
+		want          HotelDiff
+	}{
+		{
+			"deleted",
+			h, deleted,
+			HotelDiff{Removed: []room.Number{102}, Changed: map[room.Number][]string{}},
+		},
+		{
+			"restored",
+			deleted, h,
+			HotelDiff{Added: []room.Number{102}, Changed: map[room.Number][]string{}},
+		},
+		{
+			"deleted in both",
+			deleted, bothDeleted,
+			HotelDiff{Changed: map[room.Number][]string{101: {room.HeaderPrice}}},
+		},
+	}
+	for _, tt := range tests {
+		if got := Diff(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
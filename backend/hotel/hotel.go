@@ -3,22 +3,200 @@ package hotel
 import (
 	"bufio"
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"io"
+	"math"
+	"math/big"
+	"math/bits"
+	"math/rand"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 
+	"github.com/navaz-alani/hotel/date"
 	"github.com/navaz-alani/hotel/room"
 )
 
+// `RoomPredicate` reports whether a `Room` matches some criteria. It is used
+// to express queries over the rooms of a `Hotel`.
+type RoomPredicate func(r *room.Room) bool
+
+// `Hotel` is a collection of rooms, along with the attributes declared for
+// them. It is safe for concurrent use, and each method documents the locks it
+// takes: methods which only read the hotel hold its read lock and methods which
+// modify it hold its write lock, for their whole duration (input is parsed
+// before the write lock is taken).
+//
+// Rooms have their own locks. The hotel's lock is always taken before a room's
+// lock, never after: methods take the locks of rooms, one at a time, while
+// holding the hotel's lock. The rooms handed out by the hotel (e.g. by `Rooms`
+// or `Find`) may be locked by their callers at any time, but a caller holding
+// a room's lock (e.g. within `room.Room.Update`) must not call into the hotel.
+// No method holds the locks of two hotels, or two rooms, at once. Predicates
+// and callbacks (as taken by `FindN`, `Count`, `SetStateWhere` and
+// `ForEachRoom`) are called with the hotel's lock held, so they must not call
+// back into the hotel.
+//
+// Queries skip soft deleted rooms, unless their names say otherwise (as with
+// `RoomsIncludingDeleted`). Methods which target rooms by number treat a soft
+// deleted room as missing - only `RestoreRoom` targets them.
+//
+// Although the rooms are stored in a map, the iteration order of the hotel is
+// deterministic: every method which lists rooms (or their numbers) returns
+// them in ascending order of room number, so identical queries on an unchanged
+// hotel return identical results.
 type Hotel struct {
+	// version is incremented on every mutation of the hotel itself (changes
+	// to its rooms are counted by the rooms - see `room.Room.Changes`), so
+	// that cached query results can be invalidated. It is accessed atomically,
+	// and is the first field so that it is 64-bit aligned on 32-bit platforms.
+	version   uint64
 	mu        *sync.RWMutex
-	numRooms  uint
 	rooms     map[room.Number]*room.Room
 	roomAttrs []room.Attribute
+	// normalize maps attributes passed to the hotel to their canonical form -
+	// it is set when the hotel is created and never changed
+	normalize room.Normalizer
+	cache     *queryCache
+	// logger holds a loggerBox
+	logger atomic.Value
+}
+
+// `Logger` receives the log messages of a `Hotel`, such as warnings raised
+// while loading data and changes to the state of rooms. It is satisfied by
+// `*log.Logger`, amongst others.
+type Logger interface {
+	Printf(format string, args ...interface{})
+}
+
+// `nopLogger` is a `Logger` which discards all messages.
+type nopLogger struct{}
+
+func (nopLogger) Printf(string, ...interface{}) {}
+
+// `loggerBox` wraps a `Logger` so that loggers of different concrete types can
+// be stored in the same `atomic.Value`.
+type loggerBox struct {
+	Logger
+}
+
+// `newHotel` returns a pointer to an empty `Hotel`.
+func newHotel() *Hotel {
+	h := &Hotel{
+		mu:        &sync.RWMutex{},
+		rooms:     make(map[room.Number]*room.Room),
+		cache:     newQueryCache(),
+		normalize: room.LowerCase,
+	}
+	h.logger.Store(loggerBox{nopLogger{}})
+	return h
+}
+
+// `SetLogger` sets the logger to which the hotel's log messages are sent. A
+// nil `l` discards all messages (the default). It takes no lock: the logger is
+// swapped atomically, so it may be replaced while other methods run.
+func (h *Hotel) SetLogger(l Logger) {
+	if l == nil {
+		l = nopLogger{}
+	}
+	h.logger.Store(loggerBox{l})
 }
 
+// `logf` sends a log message to the hotel's logger.
+func (h *Hotel) logf(format string, args ...interface{}) {
+	h.logger.Load().(loggerBox).Printf(format, args...)
+}
+
+// `LoadOptions` configures how the data files of a `Hotel` are loaded.
+type LoadOptions struct {
+	// `Strictness` determines how errors encountered while parsing room
+	// records are handled. Defaults to `Lenient`.
+	Strictness Strictness
+	// `CommentPrefix` marks comments in the attributes data file - any line
+	// whose first token starts with it is skipped. Defaults to
+	// `DefaultCommentPrefix` when empty.
+	CommentPrefix string
+	// `Logger` receives the warnings raised while loading, as well as the
+	// hotel's subsequent log messages (see `Hotel.SetLogger`).
+	Logger Logger
+	// `Normalize` maps the attributes in both data files to their canonical
+	// form, so that the attributes of rooms match the declared ones. Defaults
+	// to `room.LowerCase` when nil.
+	Normalize room.Normalizer
+	// `SkipIdenticalDuplicates` silently skips room records which are identical
+	// (see `room.Room.Equal`) to an earlier record for the same room. Duplicate
+	// records which conflict with an earlier one always raise a warning.
+	SkipIdenticalDuplicates bool
+
+	// columnsFromHeader maps the header of the room data to its columns.
+	// Defaults to `room.ColumnsFromHeader` when nil.
+	columnsFromHeader func(header []string) (room.Columns, bool)
+	// rejectAllBad fails the load if every room record is bad, whatever the
+	// strictness
+	rejectAllBad bool
+}
+
+// `withDefaults` returns the options with the defaults filled in for any
+// unset fields.
+func (opts LoadOptions) withDefaults() LoadOptions {
+	if opts.CommentPrefix == "" {
+		opts.CommentPrefix = DefaultCommentPrefix
+	}
+	if opts.Normalize == nil {
+		opts.Normalize = room.LowerCase
+	}
+	if opts.columnsFromHeader == nil {
+		opts.columnsFromHeader = room.ColumnsFromHeader
+	}
+	return opts
+}
+
+// `Strictness` determines how errors encountered while parsing the records of
+// a data file are handled. The zero value is `Lenient`.
+type Strictness struct {
+	mode          strictnessMode
+	maxBadPercent float64
+}
+
+type strictnessMode int
+
+const (
+	strictnessLenient strictnessMode = iota
+	strictnessThreshold
+	strictnessStrict
+)
+
+var (
+	// `Lenient` skips bad records.
+	Lenient = Strictness{mode: strictnessLenient}
+	// `Strict` fails on the first bad record.
+	Strict = Strictness{mode: strictnessStrict}
+)
+
+// `Threshold` returns a `Strictness` which skips bad records, but fails if more
+// than `pct` percent of the records are bad.
+func Threshold(pct float64) Strictness {
+	return Strictness{mode: strictnessThreshold, maxBadPercent: pct}
+}
+
+// `exceeded` returns whether `numBad` bad records, out of `numRecords`, are
+// more than the strictness tolerates.
+func (s Strictness) exceeded(numBad, numRecords int) bool {
+	if s.mode != strictnessThreshold || numRecords == 0 {
+		return false
+	}
+	return float64(numBad)*100 > s.maxBadPercent*float64(numRecords)
+}
+
+// `DefaultCommentPrefix` is the comment marker used in attributes data files
+// when `LoadOptions.CommentPrefix` is not set.
+const DefaultCommentPrefix = "#"
+
 // `NewHotelFromData` creates a new `Hotel` from the attributes data contained
 // in `attrData` and the room data contained in `roomData`. Any fatal errors
 // encountered are returned by default, however with `strict` set to true, any
@@ -26,39 +204,61 @@ type Hotel struct {
 //
 // Check the 'record_formats' directory for the formats of these two data files.
 func NewHotelFromData(attrData, roomData string, strict bool) (*Hotel, error) {
-	hotel := &Hotel{
-		mu:    &sync.RWMutex{},
-		rooms: make(map[room.Number]*room.Room),
+	strictness := Lenient
+	if strict {
+		strictness = Strict
 	}
-	if err := hotel.loadAttributes(attrData); err != nil {
+	return NewHotelFromDataWithOptions(attrData, roomData, LoadOptions{
+		Strictness: strictness,
+	})
+}
+
+// `NewHotelFromDataWithOptions` is like `NewHotelFromData`, but the loading of
+// the data files is configured by `opts`.
+func NewHotelFromDataWithOptions(attrData, roomData string, opts LoadOptions) (*Hotel, error) {
+	opts = opts.withDefaults()
+	hotel := newHotel()
+	hotel.normalize = opts.Normalize
+	hotel.SetLogger(opts.Logger)
+	if err := hotel.loadAttributes(attrData, opts); err != nil {
 		return nil, err
-	} else if err = hotel.loadRooms(roomData, strict); err != nil {
+	} else if err = hotel.loadRooms(roomData, opts); err != nil {
 		return nil, err
 	}
-	hotel.numRooms = uint(len(hotel.rooms))
 	return hotel, nil
 }
 
 // `loadRooms` loads `Room`s from the data in the file with name `roomData`. Any
 // errors occurred while opening the `roomData` file or reading from it will be
-// returned. Errors encountered while parsing scanned data into a `Room` will be
-// ignored, unless the `strict` flag is true.
+// returned. Errors encountered while parsing scanned data into a `Room` are
+// handled according to `opts.Strictness`.
 //
 // The parsed rooms are loaded into the `Hotel`, `h`, directly. If an error is
 // occurred, the state of `h` is unchanged.
 //
 // Full format specs in record_formats/room_list_format
-func (h *Hotel) loadRooms(roomData string, strict bool) error {
+func (h *Hotel) loadRooms(roomData string, opts LoadOptions) error {
 	f, err := os.Open(roomData)
 	if err != nil {
 		return fmt.Errorf("rooms load err: %s", err.Error())
 	}
 	defer f.Close()
+	return h.readRooms(f, opts)
+}
 
-	csvReader := csv.NewReader(f)
+// `readRooms` is like `loadRooms`, but the room data is read from `r`.
+func (h *Hotel) readRooms(r io.Reader, opts LoadOptions) error {
+	csvReader := csv.NewReader(r)
+	// records with the wrong number of fields are bad records (handled
+	// according to the strictness), not fatal errors
+	csvReader.FieldsPerRecord = -1
+	// the column order is taken from the header, if one is present - otherwise
+	// the records are parsed positionally
+	cols := room.DefaultColumns
 	initialRecord := true
+	numRecords, numBad := 0, 0
 	rooms := make(map[room.Number]*room.Room)
-	for {
+	for line := 1; ; line++ {
 		record, err := csvReader.Read()
 		if err == io.EOF {
 			break
@@ -66,18 +266,45 @@ func (h *Hotel) loadRooms(roomData string, strict bool) error {
 		if err != nil {
 			return fmt.Errorf("load err [fatal]: %s", err.Error())
 		}
-		if initialRecord { // header
+		if initialRecord {
 			initialRecord = false
-			continue
+			// the byte order mark prefixes the file, so it is stripped here
+			// whether or not the initial record is a header
+			if len(record) > 0 {
+				record[0] = strings.TrimPrefix(record[0], room.ByteOrderMark)
+			}
+			if headerCols, ok := opts.columnsFromHeader(record); ok {
+				cols = headerCols
+				continue
+			} else if isUnrecognizedHeader(record) {
+				h.logf("load warning: skipping unrecognized header %q", record)
+				continue
+			}
 		}
-		room, err := room.NewRoomFromRecord(record, h.roomAttrs)
-		if err != nil && strict {
-			return fmt.Errorf("load err: room parse err: %s", err.Error())
+		numRecords++
+		var parsed *room.Room
+		fields, err := cols.Reorder(record)
+		if err == nil {
+			parsed, err = room.NewRoomFromRecordWith(fields, h.roomAttrs, opts.Normalize)
 		}
-		// this means that if there are multiple rooms in the room data file which
-		// have the same room number, the last such record is the one that will
-		// appear - room numbers must be unique.
-		rooms[room.ID()] = room
+		if err != nil {
+			if opts.Strictness.mode == strictnessStrict {
+				return fmt.Errorf("load err: room parse err: %s", err.Error())
+			}
+			numBad++
+			h.logf("load warning: skipping record %d: %s", line, err.Error())
+			continue
+		}
+		h.collectRoom(rooms, parsed, opts, "load warning: record", line)
+	}
+	if opts.Strictness.exceeded(numBad, numRecords) {
+		return fmt.Errorf(
+			"load err: %d of %d records bad (threshold: %g%%)",
+			numBad, numRecords, opts.Strictness.maxBadPercent,
+		)
+	}
+	if opts.rejectAllBad && numRecords > 0 && numBad == numRecords {
+		return fmt.Errorf("load err: all %d records bad", numRecords)
 	}
 
 	// modifying hotel contents
@@ -87,40 +314,1138 @@ func (h *Hotel) loadRooms(roomData string, strict bool) error {
 	for k, v := range rooms {
 		h.rooms[k] = v
 	}
+	atomic.AddUint64(&h.version, 1)
 
 	return nil
 }
 
+// `isUnrecognizedHeader` returns whether the initial record of the room data,
+// `record`, which does not map to the columns, is a header nonetheless - that
+// is, none of its cells is a room number. Otherwise, it is a (possibly bad)
+// room record.
+func isUnrecognizedHeader(record []string) bool {
+	for _, cell := range record {
+		if _, err := strconv.ParseUint(strings.TrimSpace(cell), 10, bits.UintSize); err == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// `collectRoom` adds the room `parsed`, loaded from the record (or object)
+// numbered `n`, to the loaded rooms `rooms`. If a room with the same number was
+// loaded earlier, it is replaced (so the last one wins - room numbers must be
+// unique) and a warning, prefixed by `warning`, is logged - unless the two are
+// identical and `opts.SkipIdenticalDuplicates` is set.
+func (h *Hotel) collectRoom(
+	rooms map[room.Number]*room.Room, parsed *room.Room, opts LoadOptions, warning string, n int,
+) {
+	if prev, ok := rooms[parsed.ID()]; ok {
+		if opts.SkipIdenticalDuplicates && prev.Equal(parsed) {
+			return
+		}
+		h.logf("%s %d: duplicate room %d replaces earlier one", warning, n, parsed.ID())
+	}
+	rooms[parsed.ID()] = parsed
+}
+
 // `loadAttributes` loads the attribues contained in the file with the name
 // `attrData` and returns any errors encountered. It takes only the first word
-// (consecutive non-whitespace string) on each line as the attribute - this
-// means that there can be comments on each line after the attribute in addition
-// to entire line comments i.e. lines which begin with "# ").
+// (consecutive non-whitespace string, or double-quoted string for attributes
+// containing whitespace) on each line as the attribute - this means that there
+// can be comments on each line after the attribute in addition to entire line
+// comments i.e. lines whose first word begins with `opts.CommentPrefix`.
+// Attributes are normalized with `opts.Normalize`.
 //
 // The attributes are loaded into the `Hotel`, `h`. If an error occurs, the
 // state of `h` is unchanged.
 //
 // Full format specs in record_formats/attr_list_format
-func (h *Hotel) loadAttributes(attrData string) error {
+func (h *Hotel) loadAttributes(attrData string, opts LoadOptions) error {
 	attrFile, err := os.Open(attrData)
 	if err != nil {
 		return fmt.Errorf("attributes load err: %s", err.Error())
 	}
+	defer attrFile.Close()
+	return h.readAttributes(attrFile, opts)
+}
+
+// `LoadAttributes` loads the attributes data read from `r` (for example, an
+// embedded asset or a network response) into the hotel, declaring each of its
+// attributes which are not already declared. The data is in the same format as
+// the attributes data file of `NewHotelFromData`, and is read with the default
+// `LoadOptions`, except that attributes are normalized as by `Normalize`. If an
+// error is returned, the hotel is unchanged. The data is read before the
+// hotel's write lock is taken, which is only held while the attributes are
+// declared.
+func (h *Hotel) LoadAttributes(r io.Reader) error {
+	return h.readAttributes(r, LoadOptions{Normalize: h.normalize}.withDefaults())
+}
+
+// `Normalize` returns the canonical form of the attribute `a` in the hotel, as
+// determined by `LoadOptions.Normalize` when the hotel was created (by default,
+// `a` lower-cased). The hotel's methods normalize the attributes passed to them,
+// but the methods of its rooms (such as `room.Room.Satisfies`) match attributes
+// exactly, so attributes passed to them (for example, by a `RoomPredicate`)
+// should be normalized first. It takes no lock, as the normalizer never
+// changes.
+func (h *Hotel) Normalize(a room.Attribute) room.Attribute {
+	return h.normalize(a)
+}
+
+// `normalizeAll` returns the canonical forms of the attributes `attrs`, as by
+// `Normalize`.
+func (h *Hotel) normalizeAll(attrs []room.Attribute) []room.Attribute {
+	normalized := make([]room.Attribute, len(attrs))
+	for i, attr := range attrs {
+		normalized[i] = h.normalize(attr)
+	}
+	return normalized
+}
+
+// `readAttributes` is like `loadAttributes`, but the attributes data is read
+// from `r`.
+func (h *Hotel) readAttributes(r io.Reader, opts LoadOptions) error {
+	var attrs []room.Attribute
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		attr, ok, err := parseAttributeLine(scanner.Text(), opts.CommentPrefix)
+		if err != nil {
+			return fmt.Errorf("attributes load err (line %d): %s", line, err.Error())
+		} else if !ok {
+			continue
+		}
+		attrs = append(attrs, opts.Normalize(attr))
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("attributes load err: %s", err.Error())
+	}
 
 	// modifying hotel contents
 	h.mu.Lock()
 	defer h.mu.Unlock()
+	// attributes which normalize to the same form are only declared once
+	declared := room.NewAttributeSet(h.roomAttrs...)
+	for _, attr := range attrs {
+		if !declared.Has(attr) {
+			declared.Add(attr)
+			h.roomAttrs = append(h.roomAttrs, attr)
+		}
+	}
+	return nil
+}
+
+// `parseAttributeLine` returns the attribute declared on the line `line` of an
+// attributes data file. The attribute is the first word on the line, unless
+// the line starts with a double quote, in which case it is the (possibly
+// multi-word) quoted string. The returned boolean is false if the line is
+// blank or a comment (its first word starts with `commentPrefix`). An error is
+// returned if a quoted attribute is not terminated.
+func parseAttributeLine(line, commentPrefix string) (room.Attribute, bool, error) {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, `"`) {
+		end := strings.IndexByte(line[1:], '"')
+		if end < 0 {
+			return "", false, fmt.Errorf("unterminated quoted attribute")
+		}
+		attr := line[1 : end+1]
+		return room.Attribute(attr), attr != "", nil
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 || strings.HasPrefix(fields[0], commentPrefix) {
+		return "", false, nil
+	}
+	return room.Attribute(fields[0]), true, nil
+}
+
+// `sortedNumbers` returns the numbers of the rooms in the hotel, `h`, in
+// ascending order, skipping rooms which have been soft deleted. Since map
+// iteration order is random, this is used to make the results of queries
+// deterministic.
+//
+// The caller must hold (at least) the read lock of `h`.
+func (h *Hotel) sortedNumbers() []room.Number {
+	nums := make([]room.Number, 0, len(h.rooms))
+	for n, r := range h.rooms {
+		if !r.IsDeleted() {
+			nums = append(nums, n)
+		}
+	}
+	sort.Slice(nums, func(i, j int) bool { return nums[i] < nums[j] })
+	return nums
+}
+
+// `liveRoom` returns the room of the hotel, `h`, with the number `n`, unless
+// there is none or it has been soft deleted.
+//
+// The caller must hold (at least) the read lock of `h`.
+func (h *Hotel) liveRoom(n room.Number) (*room.Room, bool) {
+	r, ok := h.rooms[n]
+	if !ok || r.IsDeleted() {
+		return nil, false
+	}
+	return r, true
+}
+
+// `allSortedNumbers` is like `sortedNumbers`, but includes the numbers of soft
+// deleted rooms.
+//
+// The caller must hold (at least) the read lock of `h`.
+func (h *Hotel) allSortedNumbers() []room.Number {
+	nums := make([]room.Number, 0, len(h.rooms))
+	for n := range h.rooms {
+		nums = append(nums, n)
+	}
+	sort.Slice(nums, func(i, j int) bool { return nums[i] < nums[j] })
+	return nums
+}
+
+// `FindN` returns at most `n` rooms of the hotel which satisfy the predicate
+// `p`. Rooms are checked in ascending order of room number and the search
+// stops as soon as `n` matches have been found. The hotel's read lock is held
+// while `p` is called, so `p` must not call back into the hotel - doing so may
+// deadlock.
+func (h *Hotel) FindN(n int, p RoomPredicate) []*room.Room {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var matches []*room.Room
+	if n <= 0 {
+		return matches
+	}
+	for _, num := range h.sortedNumbers() {
+		if r := h.rooms[num]; p(r) {
+			matches = append(matches, r)
+			if len(matches) == n {
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// `Attributes` returns the attributes declared for the hotel, sorted in
+// ascending order. The returned slice is a copy and may be modified freely. It
+// holds the hotel's read lock.
+func (h *Hotel) Attributes() []room.Attribute {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	attrs := make([]room.Attribute, len(h.roomAttrs))
+	copy(attrs, h.roomAttrs)
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i] < attrs[j] })
+	return attrs
+}
+
+// `AddAttribute` declares the attribute `a` (normalized, see `Normalize`) for
+// the hotel. It returns whether the attribute was added, which is false if `a`
+// had already been declared. It holds the hotel's write lock.
+func (h *Hotel) AddAttribute(a room.Attribute) bool {
+	a = h.normalize(a)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, attr := range h.roomAttrs {
+		if attr == a {
+			return false
+		}
+	}
+	h.roomAttrs = append(h.roomAttrs, a)
+	h.logf("declared attribute '%s'", a)
+	return true
+}
 
-	scanner := bufio.NewScanner(attrFile)
-	for scanner.Scan() {
-		attr := strings.Split(scanner.Text(), " \t")[0]
-		if attr == "" || attr == "#" {
+// `GroupByPriceBand` groups the rooms of the hotel into bands of prices of
+// width `bandSize`. Each room is keyed by the lower bound of its band, i.e.
+// `price/bandSize*bandSize`, and the rooms of a band are in ascending order of
+// room number. A `bandSize` of 0 is treated as 1. It holds the hotel's read
+// lock, reading each room under its own lock in turn.
+func (h *Hotel) GroupByPriceBand(bandSize uint) map[uint][]*room.Room {
+	if bandSize == 0 {
+		bandSize = 1
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	bands := make(map[uint][]*room.Room)
+	for _, num := range h.sortedNumbers() {
+		r := h.rooms[num]
+		band := r.Price() / bandSize * bandSize
+		bands[band] = append(bands[band], r)
+	}
+	return bands
+}
+
+// `Len` returns the number of rooms in the hotel, excluding soft deleted ones.
+// The count is derived from the rooms themselves, so it cannot go stale as
+// rooms are added or removed. It holds the hotel's read lock, reading each room
+// under its own lock in turn.
+func (h *Hotel) Len() uint {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var count uint
+	for _, r := range h.rooms {
+		if !r.IsDeleted() {
+			count++
+		}
+	}
+	return count
+}
+
+// `FreeCount` returns the number of rooms in the hotel which are free. It holds
+// the hotel's read lock, reading each room under its own lock in turn.
+func (h *Hotel) FreeCount() uint {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var count uint
+	for _, r := range h.rooms {
+		if !r.IsDeleted() && r.State() == room.StateFree {
+			count++
+		}
+	}
+	return count
+}
+
+// `Clone` returns a deep copy of the hotel, including its rooms and declared
+// attributes. The copy has its own mutexes, so it can be modified (for example
+// to explore "what-if" scenarios) without affecting `h`. The copy shares the
+// logger of `h`. It holds the read lock of `h`, reading each room under its own
+// lock in turn - the copy is not shared until it is returned.
+func (h *Hotel) Clone() *Hotel {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	clone := newHotel()
+	for num, r := range h.rooms {
+		clone.rooms[num] = r.Clone()
+	}
+	clone.roomAttrs = make([]room.Attribute, len(h.roomAttrs))
+	copy(clone.roomAttrs, h.roomAttrs)
+	clone.normalize = h.normalize
+	clone.logger.Store(h.logger.Load())
+	return clone
+}
+
+// `AddAttributeToRooms` adds the attribute `a` (normalized, see `Normalize`) to
+// each of the rooms with the numbers `nums`, under a single acquisition of the
+// hotel's write lock (changing each room under its own lock in turn). It
+// returns the numbers in `nums` for which no room exists (or the room has been
+// soft deleted).
+func (h *Hotel) AddAttributeToRooms(nums []room.Number, a room.Attribute) []room.Number {
+	a = h.normalize(a)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	atomic.AddUint64(&h.version, 1)
+	var missing []room.Number
+	for _, num := range nums {
+		r, ok := h.liveRoom(num)
+		if !ok {
+			missing = append(missing, num)
 			continue
 		}
-		h.roomAttrs = append(
-			h.roomAttrs,
-			room.Attribute(attr),
+		r.AddAttribute(a)
+		h.logf("room %d: added attribute '%s'", num, a)
+	}
+	return missing
+}
+
+// `NearestToPrice` returns the room, among those satisfying the attributes
+// `attrs`, whose price is closest to `target`. Ties are broken in favour of the
+// lower price and then the lower room number. The returned boolean is false if
+// no room satisfies `attrs`. It holds the hotel's read lock, reading each room
+// under its own lock in turn.
+func (h *Hotel) NearestToPrice(target uint, attrs []room.Attribute) (*room.Room, bool) {
+	attrs = h.normalizeAll(attrs)
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var (
+		best      *room.Room
+		bestPrice uint
+		bestDiff  uint
+	)
+	for _, num := range h.sortedNumbers() {
+		r := h.rooms[num]
+		if !r.Satisfies(attrs) {
+			continue
+		}
+		price := r.Price()
+		diff := price - target
+		if target > price {
+			diff = target - price
+		}
+		if best == nil || diff < bestDiff || (diff == bestDiff && price < bestPrice) {
+			best, bestPrice, bestDiff = r, price, diff
+		}
+	}
+	return best, best != nil
+}
+
+// `WriteJSONL` writes the rooms of the hotel to `w` in the JSON lines format,
+// i.e. one JSON object per line, in ascending order of room number. It holds
+// the hotel's read lock while writing to `w`, so a slow writer delays changes
+// to the hotel.
+func (h *Hotel) WriteJSONL(w io.Writer) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	enc := json.NewEncoder(w)
+	for _, num := range h.sortedNumbers() {
+		if err := enc.Encode(h.rooms[num]); err != nil {
+			return fmt.Errorf("jsonl export err (room: %d): %s", num, err.Error())
+		}
+	}
+	return nil
+}
+
+// `LoadRoomsJSON` loads the rooms encoded as a JSON array in `r` (in the format
+// produced by `room.Room.MarshalJSON`) into the hotel. Objects which cannot be
+// decoded into a `Room` are ignored, unless the `strict` flag is true. As with
+// CSV loading, when several rooms share a room number, the last one wins (with
+// a warning), and the attributes of the rooms are normalized (see `Normalize`).
+// It locks the hotel as `LoadRoomsJSONWithOptions` does.
+//
+// If an error is returned, the state of `h` is unchanged.
+func (h *Hotel) LoadRoomsJSON(r io.Reader, strict bool) error {
+	strictness := Lenient
+	if strict {
+		strictness = Strict
+	}
+	return h.LoadRoomsJSONWithOptions(r, LoadOptions{Strictness: strictness})
+}
+
+// `LoadRoomsJSONWithOptions` is like `LoadRoomsJSON`, but the loading is
+// configured by `opts`. Only the `Strictness` and `SkipIdenticalDuplicates`
+// options apply: the attributes are normalized as by the hotel, and warnings
+// are sent to the hotel's logger (see `SetLogger`). The data is decoded before
+// the hotel's write lock is taken, which is only held while the rooms are
+// entered.
+func (h *Hotel) LoadRoomsJSONWithOptions(r io.Reader, opts LoadOptions) error {
+	var objects []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&objects); err != nil {
+		return fmt.Errorf("json load err [fatal]: %s", err.Error())
+	}
+	numBad := 0
+	rooms := make(map[room.Number]*room.Room)
+	for i, obj := range objects {
+		rm := &room.Room{}
+		if err := json.Unmarshal(obj, rm); err != nil {
+			if opts.Strictness.mode == strictnessStrict {
+				return fmt.Errorf("json load err: room parse err: %s", err.Error())
+			}
+			numBad++
+			h.logf("json load warning: skipping object %d: %s", i, err.Error())
+			continue
+		}
+		rm.NormalizeAttributes(h.normalize)
+		h.collectRoom(rooms, rm, opts, "json load warning: object", i)
+	}
+	if opts.Strictness.exceeded(numBad, len(objects)) {
+		return fmt.Errorf(
+			"json load err: %d of %d objects bad (threshold: %g%%)",
+			numBad, len(objects), opts.Strictness.maxBadPercent,
 		)
 	}
+
+	// modifying hotel contents
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for k, v := range rooms {
+		h.rooms[k] = v
+	}
+	atomic.AddUint64(&h.version, 1)
 	return nil
 }
+
+// `ForEachRoom` calls `fn` on each room of the hotel, in ascending order of
+// room number, stopping as soon as `fn` returns false. The hotel's read lock is
+// held for the duration of the iteration, so `fn` must not call back into the
+// hotel - doing so may deadlock.
+func (h *Hotel) ForEachRoom(fn func(*room.Room) bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, num := range h.sortedNumbers() {
+		if !fn(h.rooms[num]) {
+			return
+		}
+	}
+}
+
+// `Rank` returns the rooms of the hotel in the state `state`, ordered by
+// descending relevance score. The score of a room is the sum of the weights (in
+// `weights`) of the attributes it has. Ties are broken in favour of the lower
+// price and then the lower room number. It holds the hotel's read lock, reading
+// each room under its own lock in turn.
+func (h *Hotel) Rank(weights map[room.Attribute]float64, state room.State) []*room.Room {
+	// the weights of attributes with the same canonical form add up
+	normalized := make(map[room.Attribute]float64, len(weights))
+	for attr, weight := range weights {
+		normalized[h.normalize(attr)] += weight
+	}
+	weights = normalized
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	type scored struct {
+		r     *room.Room
+		price uint
+		score float64
+	}
+	var matches []scored
+	for _, num := range h.sortedNumbers() {
+		r := h.rooms[num]
+		if r.State() != state {
+			continue
+		}
+		var score float64
+		for attr, weight := range weights {
+			if r.HasAttribute(attr) {
+				score += weight
+			}
+		}
+		matches = append(matches, scored{r, r.Price(), score})
+	}
+	// stable, so that ties in score and price remain in room number order
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].price < matches[j].price
+	})
+	ranked := make([]*room.Room, len(matches))
+	for i, m := range matches {
+		ranked[i] = m.r
+	}
+	return ranked
+}
+
+// `RoomsWithoutAttribute` returns the rooms of the hotel which do not have the
+// attribute `a`, in ascending order of room number. It holds the hotel's read
+// lock, reading each room under its own lock in turn.
+func (h *Hotel) RoomsWithoutAttribute(a room.Attribute) []*room.Room {
+	a = h.normalize(a)
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var rooms []*room.Room
+	for _, num := range h.sortedNumbers() {
+		if r := h.rooms[num]; !r.HasAttribute(a) {
+			rooms = append(rooms, r)
+		}
+	}
+	return rooms
+}
+
+// `Search` returns the rooms of the hotel matching the free-text query `q`, in
+// ascending order of room number. A room matches if its number starts with `q`
+// or if any of its attributes contains `q` (case-insensitively). An empty query
+// matches every room. It holds the hotel's read lock, reading each room under
+// its own lock in turn.
+func (h *Hotel) Search(q string) []*room.Room {
+	q = strings.ToLower(strings.TrimSpace(q))
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var rooms []*room.Room
+	for _, num := range h.sortedNumbers() {
+		r := h.rooms[num]
+		if strings.HasPrefix(strconv.FormatUint(uint64(num), 10), q) {
+			rooms = append(rooms, r)
+			continue
+		}
+		for _, attr := range r.Attributes() {
+			if strings.Contains(strings.ToLower(string(attr)), q) {
+				rooms = append(rooms, r)
+				break
+			}
+		}
+	}
+	return rooms
+}
+
+// `declaredKeys` returns the keys (see `room.Attribute.Split`) of the
+// attributes declared for the hotel. A keyed attribute is declared by its key,
+// whether the key is declared bare (e.g. "view") or with any value (e.g.
+// "view=sea").
+//
+// The caller must hold (at least) the read lock of `h`.
+func (h *Hotel) declaredKeys() room.AttributeSet {
+	keys := room.NewAttributeSet()
+	for _, attr := range h.roomAttrs {
+		key, _, _ := attr.Split()
+		keys.Add(room.Attribute(key))
+	}
+	return keys
+}
+
+// `UnusedAttributes` returns the attributes declared for the hotel which no
+// room has, in ascending order. Keyed attributes are compared by key, as in
+// `Validate`. It holds the hotel's read lock, reading each
+// room under its own lock in turn.
+func (h *Hotel) UnusedAttributes() []room.Attribute {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	used := room.NewAttributeSet()
+	for _, num := range h.sortedNumbers() {
+		for _, attr := range h.rooms[num].Attributes() {
+			key, _, _ := attr.Split()
+			used.Add(room.Attribute(key))
+		}
+	}
+	unused := room.NewAttributeSet()
+	for _, attr := range h.roomAttrs {
+		if key, _, _ := attr.Split(); !used.Has(room.Attribute(key)) {
+			unused.Add(attr)
+		}
+	}
+	return unused.Slice()
+}
+
+// `RoomsByState` returns the rooms of the hotel grouped by state. Every room
+// state is present in the returned map (with an empty, non-nil slice if no room
+// is in that state) and each group is in ascending order of room number. It
+// holds the hotel's read lock, reading each room under its own lock in turn.
+func (h *Hotel) RoomsByState() map[room.State][]*room.Room {
+	groups := map[room.State][]*room.Room{
+		room.StateOccupied:    {},
+		room.StateUnavailable: {},
+		room.StateFree:        {},
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, num := range h.sortedNumbers() {
+		r := h.rooms[num]
+		state := r.State()
+		groups[state] = append(groups[state], r)
+	}
+	return groups
+}
+
+// `AttributePremium` returns the difference between the mean price of the
+// rooms which have the attribute `a` and the mean price of those which do not.
+// The returned boolean is false (and the premium 0) unless both groups of rooms
+// are non-empty. It holds the hotel's read lock, reading each room under its
+// own lock in turn.
+func (h *Hotel) AttributePremium(a room.Attribute) (float64, bool) {
+	a = h.normalize(a)
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var (
+		withSum, withoutSum     float64
+		withCount, withoutCount int
+	)
+	for _, num := range h.sortedNumbers() {
+		r := h.rooms[num]
+		if r.HasAttribute(a) {
+			withSum += float64(r.Price())
+			withCount++
+		} else {
+			withoutSum += float64(r.Price())
+			withoutCount++
+		}
+	}
+	if withCount == 0 || withoutCount == 0 {
+		return 0, false
+	}
+	return withSum/float64(withCount) - withoutSum/float64(withoutCount), true
+}
+
+// `RandomFreeRoom` returns a free room of the hotel, chosen at random using
+// `rng`. For a given hotel, the choice depends only on the state of `rng`, so
+// seeding it makes the selection reproducible. The returned boolean is false
+// if no room is free. It holds the hotel's read lock, reading each room under
+// its own lock in turn.
+func (h *Hotel) RandomFreeRoom(rng *rand.Rand) (*room.Room, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var free []*room.Room
+	for _, num := range h.sortedNumbers() {
+		if r := h.rooms[num]; r.State() == room.StateFree {
+			free = append(free, r)
+		}
+	}
+	if len(free) == 0 {
+		return nil, false
+	}
+	return free[rng.Intn(len(free))], true
+}
+
+// `SetStateWhere` sets the state of every room of the hotel which satisfies the
+// predicate `p` to `s`, returning the number of rooms whose state changed. If
+// `s` is not a recognized state, no room is changed. The hotel's write lock is
+// held while `p` is called, so `p` must not call back into the hotel - doing so
+// will deadlock.
+func (h *Hotel) SetStateWhere(p RoomPredicate, s room.State) int {
+	if !room.IsValidState(s) {
+		h.logf("set state: ignoring invalid state '%s'", s)
+		return 0
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	changed := 0
+	for _, num := range h.sortedNumbers() {
+		r := h.rooms[num]
+		if !p(r) || r.State() == s {
+			continue
+		}
+		r.SetState(s)
+		h.logf("room %d: state set to %s", num, s)
+		changed++
+	}
+	if changed > 0 {
+		atomic.AddUint64(&h.version, 1)
+	}
+	return changed
+}
+
+// `Validate` checks the integrity of the hotel, returning every problem found
+// (or nil if there are none). It checks that every room is in a recognized
+// state, has a positive price, has only declared attributes and is stored under
+// its own room number. A keyed attribute (such as "view=sea") is declared by
+// its key, whether the key is declared bare ("view") or with any value. It
+// holds the hotel's read lock, reading each room under its own lock in turn.
+func (h *Hotel) Validate() []error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	declared := h.declaredKeys()
+	var errs []error
+	for _, num := range h.allSortedNumbers() {
+		r := h.rooms[num]
+		if r == nil {
+			errs = append(errs, fmt.Errorf("room %d: missing room", num))
+			continue
+		}
+		if id := r.ID(); id != num {
+			errs = append(errs, fmt.Errorf("room %d: stored under number %d", id, num))
+		}
+		if state := r.State(); !room.IsValidState(state) {
+			errs = append(errs, fmt.Errorf("room %d: unrecognized state '%s'", num, state))
+		}
+		if r.Price() == 0 {
+			errs = append(errs, fmt.Errorf("room %d: price must be positive", num))
+		}
+		for _, attr := range r.Attributes() {
+			if key, _, _ := attr.Split(); !declared.Has(room.Attribute(key)) {
+				errs = append(errs, fmt.Errorf("room %d: undeclared attribute '%s'", num, attr))
+			}
+		}
+	}
+	return errs
+}
+
+// `RenameAttribute` renames the attribute `oldAttr` to `newAttr`, both in the
+// hotel's declared attributes and on every room which has it. It returns the
+// number of rooms changed. An error is returned, and nothing is changed, if
+// `newAttr` is already declared. It holds the hotel's write lock, changing each
+// room under its own lock in turn.
+func (h *Hotel) RenameAttribute(oldAttr, newAttr room.Attribute) (int, error) {
+	oldAttr, newAttr = h.normalize(oldAttr), h.normalize(newAttr)
+	if oldAttr == newAttr {
+		return 0, nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, attr := range h.roomAttrs {
+		if attr == newAttr {
+			return 0, fmt.Errorf("rename err: attribute '%s' already declared", newAttr)
+		}
+	}
+	for i, attr := range h.roomAttrs {
+		if attr == oldAttr {
+			h.roomAttrs[i] = newAttr
+		}
+	}
+	changed := 0
+	for _, num := range h.allSortedNumbers() {
+		r := h.rooms[num]
+		if r.RemoveAttribute(oldAttr) {
+			r.AddAttribute(newAttr)
+			changed++
+		}
+	}
+	if changed > 0 {
+		atomic.AddUint64(&h.version, 1)
+	}
+	h.logf("renamed attribute '%s' to '%s' (%d rooms)", oldAttr, newAttr, changed)
+	return changed, nil
+}
+
+// `TotalNightlyValue` returns the sum of the nightly prices of all the rooms of
+// the hotel. The sum is accumulated without overflow, but if it exceeds the
+// largest `uint`, that value is returned instead. It holds the hotel's read
+// lock, reading each room under its own lock in turn.
+func (h *Hotel) TotalNightlyValue() uint {
+	return h.nightlyValue(func(*room.Room) bool { return true })
+}
+
+// `FreeNightlyValue` is like `TotalNightlyValue`, but only sums the prices of
+// free rooms.
+func (h *Hotel) FreeNightlyValue() uint {
+	return h.nightlyValue(func(r *room.Room) bool {
+		return r.State() == room.StateFree
+	})
+}
+
+// `nightlyValue` returns the (saturated) sum of the prices of the rooms which
+// satisfy the predicate `p`.
+func (h *Hotel) nightlyValue(p RoomPredicate) uint {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	total := new(big.Int)
+	price := new(big.Int)
+	for _, num := range h.sortedNumbers() {
+		if r := h.rooms[num]; p(r) {
+			total.Add(total, price.SetUint64(uint64(r.Price())))
+		}
+	}
+	const maxUint = ^uint(0)
+	if !total.IsUint64() || total.Uint64() > uint64(maxUint) {
+		return maxUint
+	}
+	return uint(total.Uint64())
+}
+
+// `Has` returns whether the hotel has a room with the number `n`, which has not
+// been soft deleted. It holds the hotel's read lock, reading each room under
+// its own lock in turn.
+func (h *Hotel) Has(n room.Number) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	_, ok := h.liveRoom(n)
+	return ok
+}
+
+// `SuggestUpgrade` returns the cheapest free room which has all the attributes
+// of the room with the number `from`, plus at least one more. Ties in price are
+// broken in favour of the lower room number. The returned boolean is false if
+// there is no room `from` or no such upgrade. It holds the hotel's read lock,
+// reading each room under its own lock in turn.
+func (h *Hotel) SuggestUpgrade(from room.Number) (*room.Room, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	current, ok := h.liveRoom(from)
+	if !ok {
+		return nil, false
+	}
+	attrs := current.Attributes()
+	var best *room.Room
+	var bestPrice uint
+	for _, num := range h.sortedNumbers() {
+		r := h.rooms[num]
+		if num == from || r.State() != room.StateFree {
+			continue
+		}
+		if r.AttributeCount() <= len(attrs) || !r.Satisfies(attrs) {
+			continue
+		}
+		if price := r.Price(); best == nil || price < bestPrice {
+			best, bestPrice = r, price
+		}
+	}
+	return best, best != nil
+}
+
+// `Rooms` returns the rooms of the hotel, in ascending order of room number.
+// Soft deleted rooms are skipped - use `RoomsIncludingDeleted` to include them.
+// It holds the hotel's read lock while listing the rooms, which callers may
+// then lock themselves (see `Hotel`).
+func (h *Hotel) Rooms() []*room.Room {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.roomsByNumber(h.sortedNumbers())
+}
+
+// `RoomsIncludingDeleted` is like `Rooms`, but includes soft deleted rooms. It
+// holds the hotel's read lock, but not the locks of the rooms.
+func (h *Hotel) RoomsIncludingDeleted() []*room.Room {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.roomsByNumber(h.allSortedNumbers())
+}
+
+// `roomsByNumber` returns the rooms with the numbers `nums`, in the same order.
+//
+// The caller must hold (at least) the read lock of `h`.
+func (h *Hotel) roomsByNumber(nums []room.Number) []*room.Room {
+	rooms := make([]*room.Room, len(nums))
+	for i, num := range nums {
+		rooms[i] = h.rooms[num]
+	}
+	return rooms
+}
+
+// `SoftDeleteRoom` soft deletes the room with the number `n` (see
+// `room.Room.SoftDelete`), so that it is skipped by the hotel's queries. It
+// returns false if there is no such room, or it is already deleted. It holds
+// the hotel's write lock, and then the room's.
+func (h *Hotel) SoftDeleteRoom(n room.Number) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r, ok := h.liveRoom(n)
+	if !ok {
+		return false
+	}
+	r.SoftDelete()
+	atomic.AddUint64(&h.version, 1)
+	h.logf("room %d: deleted", n)
+	return true
+}
+
+// `RestoreRoom` recovers the soft deleted room with the number `n`. It returns
+// false if there is no such room. It holds the hotel's write lock, and then the
+// room's.
+func (h *Hotel) RestoreRoom(n room.Number) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r, ok := h.rooms[n]
+	if !ok {
+		return false
+	}
+	r.Restore()
+	atomic.AddUint64(&h.version, 1)
+	h.logf("room %d: restored", n)
+	return true
+}
+
+// `Numbers` returns the numbers of the rooms of the hotel, in ascending order.
+// Soft deleted rooms are skipped. It holds the hotel's read lock, reading each
+// room under its own lock in turn.
+func (h *Hotel) Numbers() []room.Number {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.sortedNumbers()
+}
+
+// `Fingerprint` returns a hash of the contents of the hotel: its declared
+// attributes and the canonical record (see `room.Room.ToRecord`) of each of its
+// rooms, in ascending order of room number. Hotels with the same contents have
+// the same fingerprint, regardless of the order in which they were loaded, and
+// any change to a room or to the declared attributes changes it. Soft deleted
+// rooms are skipped. It holds the hotel's read lock, reading each room under
+// its own lock in turn.
+func (h *Hotel) Fingerprint() uint64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	hash := fnv.New64a()
+	// fields are NUL-terminated and records newline-terminated, so that
+	// different contents cannot serialize to the same bytes
+	attrs := room.NewAttributeSet(h.roomAttrs...).Slice()
+	for _, attr := range attrs {
+		io.WriteString(hash, string(attr)+"\x00")
+	}
+	io.WriteString(hash, "\n")
+	for _, num := range h.sortedNumbers() {
+		for _, field := range h.rooms[num].ToRecord() {
+			io.WriteString(hash, field+"\x00")
+		}
+		io.WriteString(hash, "\n")
+	}
+	return hash.Sum64()
+}
+
+// `WriteCSVColumns` writes the rooms of the hotel to `w` as CSV, in ascending
+// order of room number, with only the columns named in `cols` (see
+// `room.EntryForHeader` for the recognized names). The first row is a header
+// holding the names in `cols`. An error is returned, before anything is
+// written, if any name is not recognized. It holds the hotel's read lock while
+// writing to `w`, so a slow writer delays changes to the hotel.
+func (h *Hotel) WriteCSVColumns(w io.Writer, cols []string) error {
+	entries := make([]int, len(cols))
+	for i, col := range cols {
+		entry, ok := room.EntryForHeader(col)
+		if !ok {
+			return fmt.Errorf("csv export err: unknown column '%s'", col)
+		}
+		entries[i] = entry
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	csvWriter := csv.NewWriter(w)
+	if err := csvWriter.Write(cols); err != nil {
+		return fmt.Errorf("csv export err: %s", err.Error())
+	}
+	row := make([]string, len(entries))
+	for _, num := range h.sortedNumbers() {
+		record := h.rooms[num].ToRecord()
+		for i, entry := range entries {
+			row[i] = record[entry]
+		}
+		if err := csvWriter.Write(row); err != nil {
+			return fmt.Errorf("csv export err (room: %d): %s", num, err.Error())
+		}
+	}
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		return fmt.Errorf("csv export err: %s", err.Error())
+	}
+	return nil
+}
+
+// `ScalePrices` multiplies the price of every room of the hotel by `factor`,
+// rounding to the nearest whole price and clamping the result to at least 1.
+// It returns the number of rooms whose price changed. If `factor` is not a
+// finite number, no price is changed. It holds the hotel's write lock, changing
+// each room under its own lock in turn.
+func (h *Hotel) ScalePrices(factor float64) int {
+	if math.IsNaN(factor) || math.IsInf(factor, 0) {
+		h.logf("scale prices: ignoring invalid factor %g", factor)
+		return 0
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	changed := 0
+	for _, num := range h.sortedNumbers() {
+		r := h.rooms[num]
+		old := r.Price()
+		scaled := math.Round(float64(old) * factor)
+		var price uint
+		switch {
+		case scaled < 1:
+			price = 1
+		case scaled >= math.Ldexp(1, bits.UintSize):
+			price = ^uint(0)
+		default:
+			price = uint(scaled)
+		}
+		if price != old {
+			r.SetPrice(price)
+			changed++
+		}
+	}
+	if changed > 0 {
+		atomic.AddUint64(&h.version, 1)
+	}
+	h.logf("scaled prices by %g (%d rooms)", factor, changed)
+	return changed
+}
+
+// `BestMatch` returns the room in the state `state` which has the most of the
+// attributes `attrs`, along with how many of them it has. Ties are broken in
+// favour of the lower price and then the lower room number. If no room is in
+// `state`, nil and 0 are returned. It holds the hotel's read lock, reading each
+// room under its own lock in turn.
+func (h *Hotel) BestMatch(attrs []room.Attribute, state room.State) (*room.Room, int) {
+	attrs = h.normalizeAll(attrs)
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var (
+		best      *room.Room
+		bestPrice uint
+		bestCount int
+	)
+	for _, num := range h.sortedNumbers() {
+		r := h.rooms[num]
+		if r.State() != state {
+			continue
+		}
+		count := 0
+		for _, attr := range attrs {
+			if r.HasAttribute(attr) {
+				count++
+			}
+		}
+		price := r.Price()
+		if best == nil || count > bestCount || (count == bestCount && price < bestPrice) {
+			best, bestPrice, bestCount = r, price, count
+		}
+	}
+	return best, bestCount
+}
+
+// `ApplyPriceSheet` updates the prices of the hotel's rooms from the CSV price
+// sheet read from `r`, whose rows are of the form `number,price` (a leading
+// header row is allowed). It returns the numbers in the sheet for which no room
+// exists. Malformed rows, including those with a zero price, are skipped - see
+// `ApplyPriceSheetStrict`. The sheet is read before the hotel's write lock is
+// taken, which is only held while the prices are changed.
+func (h *Hotel) ApplyPriceSheet(r io.Reader) ([]room.Number, error) {
+	return h.applyPriceSheet(r, false)
+}
+
+// `ApplyPriceSheetStrict` is like `ApplyPriceSheet`, but returns an error if any
+// row is malformed, in which case no price is changed.
+func (h *Hotel) ApplyPriceSheetStrict(r io.Reader) ([]room.Number, error) {
+	return h.applyPriceSheet(r, true)
+}
+
+// `applyPriceSheet` implements `ApplyPriceSheet` and `ApplyPriceSheetStrict`.
+func (h *Hotel) applyPriceSheet(r io.Reader, strict bool) ([]room.Number, error) {
+	type priceUpdate struct {
+		num   room.Number
+		price uint
+	}
+	csvReader := csv.NewReader(r)
+	csvReader.FieldsPerRecord = -1
+	var updates []priceUpdate
+	for line := 1; ; line++ {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("price sheet err [fatal]: %s", err.Error())
+		}
+		var num, price uint64
+		if len(record) != 2 {
+			err = fmt.Errorf("expected 2 entries, got %d", len(record))
+		} else {
+			num, err = strconv.ParseUint(strings.TrimSpace(record[0]), 10, bits.UintSize)
+			if err != nil && line == 1 {
+				// a header - a leading row of the right shape, without a room
+				// number
+				continue
+			}
+		}
+		if err == nil {
+			price, err = strconv.ParseUint(strings.TrimSpace(record[1]), 10, bits.UintSize)
+		}
+		if err == nil && price == 0 {
+			err = fmt.Errorf("price must be positive")
+		}
+		if err != nil {
+			if strict {
+				return nil, fmt.Errorf("price sheet err (row %d): %s", line, err.Error())
+			}
+			h.logf("price sheet warning: skipping row %d: %s", line, err.Error())
+			continue
+		}
+		updates = append(updates, priceUpdate{room.Number(num), uint(price)})
+	}
+
+	// modifying hotel contents
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var missing []room.Number
+	for _, u := range updates {
+		r, ok := h.liveRoom(u.num)
+		if !ok {
+			missing = append(missing, u.num)
+			continue
+		}
+		r.SetPrice(u.price)
+	}
+	atomic.AddUint64(&h.version, 1)
+	return missing, nil
+}
+
+// `Count` returns the number of rooms of the hotel which satisfy the predicate
+// `p`, without collecting them. The hotel's read lock is held while `p` is
+// called, so `p` must not call back into the hotel - doing so may deadlock.
+func (h *Hotel) Count(p RoomPredicate) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	count := 0
+	for _, r := range h.rooms {
+		if !r.IsDeleted() && p(r) {
+			count++
+		}
+	}
+	return count
+}
+
+// `ModifiedSince` returns the rooms of the hotel which were last modified on or
+// after the date `d`, in ascending order of room number. Rooms which have not
+// been modified since they were created are skipped. It holds the hotel's read
+// lock, reading each room under its own lock in turn.
+func (h *Hotel) ModifiedSince(d *date.Date) []*room.Room {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var rooms []*room.Room
+	for _, num := range h.sortedNumbers() {
+		r := h.rooms[num]
+		if modified := r.Modified(); modified != nil && modified.Compare(d) >= 0 {
+			rooms = append(rooms, r)
+		}
+	}
+	return rooms
+}
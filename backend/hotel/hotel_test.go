@@ -0,0 +1,1272 @@
+package hotel
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/navaz-alani/hotel/date"
+	"github.com/navaz-alani/hotel/room"
+)
+
+const (
+	testAttrData = `# amenities
+balcony
+sea_view
+minibar
+`
+	testRoomData = `room_number,price,state,attributes
+101,100,FREE,"balcony,sea_view"
+102,80,FREE,balcony
+103,120,OCCUPIED,"minibar,sea_view"
+104,60,UNAVAILABLE,
+`
+)
+
+// `writeTestFile` writes `data` to a temporary file named `name`, returning
+// its path.
+func writeTestFile(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("writing %s: %s", name, err.Error())
+	}
+	return path
+}
+
+// `loadTestHotel` returns the hotel loaded from the attributes data `attrData`
+// and the room data `roomData`, with the options `opts`.
+func loadTestHotel(t *testing.T, attrData, roomData string, opts LoadOptions) (*Hotel, error) {
+	t.Helper()
+	return NewHotelFromDataWithOptions(
+		writeTestFile(t, "attributes.txt", attrData),
+		writeTestFile(t, "rooms.csv", roomData),
+		opts,
+	)
+}
+
+// `newTestHotel` returns a hotel loaded (strictly) from the attributes data
+// `attrData` and the room data `roomData`.
+func newTestHotel(t *testing.T, attrData, roomData string) *Hotel {
+	t.Helper()
+	h, err := loadTestHotel(t, attrData, roomData, LoadOptions{Strictness: Strict})
+	if err != nil {
+		t.Fatalf("loading hotel: %s", err.Error())
+	}
+	return h
+}
+
+// `captureLogger` is a `Logger` which records the messages it receives.
+type captureLogger struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (l *captureLogger) Printf(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.msgs = append(l.msgs, fmt.Sprintf(format, args...))
+}
+
+// `matching` returns the number of messages received which contain `substr`.
+func (l *captureLogger) matching(substr string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := 0
+	for _, msg := range l.msgs {
+		if strings.Contains(msg, substr) {
+			n++
+		}
+	}
+	return n
+}
+
+// `numbersOf` returns the numbers of the rooms `rooms`, in the same order.
+func numbersOf(rooms []*room.Room) []room.Number {
+	nums := make([]room.Number, len(rooms))
+	for i, r := range rooms {
+		nums[i] = r.ID()
+	}
+	return nums
+}
+
+// `findUncached` is `Find`, without the cache.
+func findUncached(h *Hotel, q Query) []room.Number {
+	q.Attributes = h.normalizeAll(q.Attributes)
+	var nums []room.Number
+	for _, r := range h.Rooms() {
+		if q.matches(r) {
+			nums = append(nums, r.ID())
+		}
+	}
+	return nums
+}
+
+func TestFindMatchesUncached(t *testing.T) {
+	h := newTestHotel(t, testAttrData, testRoomData)
+	tests := []struct {
+		name string
+		q    Query
+		want []room.Number
+	}{
+		{"everything", Query{}, []room.Number{101, 102, 103, 104}},
+		{"by state", Query{State: room.StateFree}, []room.Number{101, 102}},
+		{"by max price", Query{MaxPrice: 100}, []room.Number{101, 102, 104}},
+		{"by attribute", Query{Attributes: []room.Attribute{"sea_view"}}, []room.Number{101, 103}},
+		{"by attribute, differently cased", Query{Attributes: []room.Attribute{"Sea_View"}}, []room.Number{101, 103}},
+		{
+			"by everything",
+			Query{
+				Attributes: []room.Attribute{"balcony", "sea_view"},
+				State:      room.StateFree,
+				MaxPrice:   100,
+			},
+			[]room.Number{101},
+		},
+		{"no match", Query{Attributes: []room.Attribute{"jacuzzi"}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the second call is answered from the cache
+			for i := 0; i < 2; i++ {
+				got := numbersOf(h.Find(tt.q))
+				if len(got) == 0 {
+					got = nil
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("call %d: got %v, want %v", i+1, got, tt.want)
+				}
+				if uncached := findUncached(h, tt.q); !reflect.DeepEqual(got, uncached) {
+					t.Errorf("call %d: got %v, uncached %v", i+1, got, uncached)
+				}
+			}
+		})
+	}
+}
+
+func TestFindCacheInvalidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		q      Query
+		mutate func(t *testing.T, h *Hotel)
+	}{
+		{
+			"state set on room",
+			Query{State: room.StateFree},
+			func(t *testing.T, h *Hotel) { h.Rooms()[0].SetState(room.StateOccupied) },
+		},
+		{
+			"price set on room",
+			Query{MaxPrice: 100},
+			func(t *testing.T, h *Hotel) { h.Rooms()[0].SetPrice(150) },
+		},
+		{
+			"attribute added to room",
+			Query{Attributes: []room.Attribute{"minibar"}},
+			func(t *testing.T, h *Hotel) { h.Rooms()[0].AddAttribute("minibar") },
+		},
+		{
+			"room deactivated",
+			Query{State: room.StateFree},
+			func(t *testing.T, h *Hotel) { h.Rooms()[1].Deactivate() },
+		},
+		{
+			"room updated",
+			Query{State: room.StateFree, MaxPrice: 100},
+			func(t *testing.T, h *Hotel) {
+				h.Rooms()[3].Update(func(mut *room.RoomMutator) {
+					mut.SetPrice(90)
+					mut.SetState(room.StateFree)
+				})
+			},
+		},
+		{
+			"room soft deleted",
+			Query{},
+			func(t *testing.T, h *Hotel) { h.SoftDeleteRoom(102) },
+		},
+		{
+			"state set where",
+			Query{State: room.StateOccupied},
+			func(t *testing.T, h *Hotel) {
+				h.SetStateWhere(func(r *room.Room) bool { return r.Price() < 100 }, room.StateOccupied)
+			},
+		},
+		{
+			"rooms loaded from json",
+			Query{State: room.StateFree},
+			func(t *testing.T, h *Hotel) {
+				err := h.LoadRoomsJSON(strings.NewReader(
+					`[{"id": 105, "price": 70, "state": "FREE", "attributes": []}]`,
+				), true)
+				if err != nil {
+					t.Fatalf("LoadRoomsJSON: %s", err.Error())
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHotel(t, testAttrData, testRoomData)
+			before := numbersOf(h.Find(tt.q))
+			tt.mutate(t, h)
+			got := numbersOf(h.Find(tt.q))
+			if len(got) == 0 {
+				got = nil
+			}
+			if want := findUncached(h, tt.q); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v (before: %v), want %v", got, before, want)
+			}
+			if reflect.DeepEqual(got, before) {
+				t.Errorf("mutation did not change the results (%v)", got)
+			}
+		})
+	}
+}
+
+func TestFindCacheBounded(t *testing.T) {
+	h := newTestHotel(t, testAttrData, testRoomData)
+	for price := uint(1); price <= 2*maxCachedQueries; price++ {
+		h.Find(Query{MaxPrice: price})
+	}
+	h.cache.mu.Lock()
+	defer h.cache.mu.Unlock()
+	if got := len(h.cache.results); got > maxCachedQueries {
+		t.Errorf("got %d cached queries, want at most %d", got, maxCachedQueries)
+	}
+}
+
+// `TestConcurrentAccess` exercises the locking contract of `Hotel` - run it
+// with the race detector (`go test -race`).
+func TestConcurrentAccess(t *testing.T) {
+	h := newTestHotel(t, testAttrData, testRoomData)
+	other := h.Clone()
+	const iterations = 200
+	workers := []func(i int){
+		func(int) { h.Find(Query{State: room.StateFree}) },
+		func(int) { h.Find(Query{Attributes: []room.Attribute{"balcony"}}) },
+		func(i int) {
+			state := room.State(room.StateFree)
+			if i%2 == 0 {
+				state = room.StateOccupied
+			}
+			h.SetStateWhere(func(r *room.Room) bool { return r.HasAttribute("balcony") }, state)
+		},
+		func(int) {
+			err := h.LoadRoomsJSON(strings.NewReader(
+				`[{"id": 105, "price": 70, "state": "FREE", "attributes": ["minibar"]}]`,
+			), true)
+			if err != nil {
+				t.Errorf("LoadRoomsJSON: %s", err.Error())
+			}
+		},
+		func(int) {
+			if err := h.LoadAttributes(strings.NewReader("jacuzzi\n")); err != nil {
+				t.Errorf("LoadAttributes: %s", err.Error())
+			}
+		},
+		func(int) { Diff(h, other) },
+		func(int) { Diff(other, h) },
+		func(i int) { other.Rooms()[0].SetPrice(uint(100 + i)) },
+		func(int) { h.Count(func(r *room.Room) bool { return r.State() == room.StateFree }) },
+		func(int) { h.Fingerprint() },
+	}
+	var wg sync.WaitGroup
+	for _, work := range workers {
+		wg.Add(1)
+		go func(work func(int)) {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				work(i)
+			}
+		}(work)
+	}
+	wg.Wait()
+	if errs := h.Validate(); len(errs) != 0 {
+		t.Errorf("invalid hotel after concurrent access: %v", errs)
+	}
+}
+
+// `isFree` is a `RoomPredicate` matching free rooms.
+func isFree(r *room.Room) bool {
+	return r.State() == room.StateFree
+}
+
+func TestLoadColumns(t *testing.T) {
+	tests := []struct {
+		name     string
+		roomData string
+	}{
+		{"default order", testRoomData},
+		{"no header", strings.SplitN(testRoomData, "\n", 2)[1]},
+		{
+			"other order",
+			`state,attributes,price,room_number
+FREE,"balcony,sea_view",100,101
+FREE,balcony,80,102
+OCCUPIED,"minibar,sea_view",120,103
+UNAVAILABLE,,60,104
+`,
+		},
+		{"byte order mark", room.ByteOrderMark + testRoomData},
+		{"byte order mark, no header", room.ByteOrderMark + strings.SplitN(testRoomData, "\n", 2)[1]},
+		{
+			"padded header",
+			" Room_Number , price,STATE ,  attributes\n" + strings.SplitN(testRoomData, "\n", 2)[1],
+		},
+	}
+	want := newTestHotel(t, testAttrData, testRoomData).Fingerprint()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHotel(t, testAttrData, tt.roomData)
+			if got := h.Fingerprint(); got != want {
+				t.Errorf("loaded different rooms: %v", h.Rooms())
+			}
+		})
+	}
+}
+
+func TestLoadInitialRecord(t *testing.T) {
+	tests := []struct {
+		name       string
+		roomData   string
+		strictness Strictness
+		ok         bool
+		want       []room.Number
+	}{
+		{"bad room, strict", "101,abc,FREE,balcony\n102,5,FREE,balcony\n", Strict, false, nil},
+		{"bad room, lenient", "101,abc,FREE,balcony\n102,5,FREE,balcony\n", Lenient, true, []room.Number{102}},
+		{"bad room, over threshold", "101,abc,FREE,balcony\n102,5,FREE,balcony\n", Threshold(40), false, nil},
+		{"bad room, wrong field count", "101,5\n102,5,FREE,balcony\n", Strict, false, nil},
+		{"unrecognized header", "Room,Cost,Status,Tags\n102,5,FREE,balcony\n", Strict, true, []room.Number{102}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := loadTestHotel(t, testAttrData, tt.roomData, LoadOptions{Strictness: tt.strictness})
+			if (err == nil) != tt.ok {
+				t.Fatalf("got error %v, want ok: %t", err, tt.ok)
+			}
+			if !tt.ok {
+				return
+			}
+			if got := numbersOf(h.Rooms()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got rooms %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadStrictness(t *testing.T) {
+	// 1 bad record (too short) of 5
+	roomData := `101,100,FREE,balcony
+102,80,FREE,balcony
+103,120
+104,60,UNAVAILABLE,
+105,70,FREE,
+`
+	tests := []struct {
+		name       string
+		strictness Strictness
+		ok         bool
+	}{
+		{"lenient", Lenient, true},
+		{"under threshold", Threshold(25), true},
+		{"at threshold", Threshold(20), true},
+		{"over threshold", Threshold(10), false},
+		{"strict", Strict, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := loadTestHotel(t, testAttrData, roomData, LoadOptions{Strictness: tt.strictness})
+			if (err == nil) != tt.ok {
+				t.Fatalf("got error %v, want ok: %t", err, tt.ok)
+			}
+			if tt.ok && h.Len() != 4 {
+				t.Errorf("got %d rooms, want 4", h.Len())
+			}
+		})
+	}
+}
+
+func TestLoadLogsWarnings(t *testing.T) {
+	logger := &captureLogger{}
+	_, err := loadTestHotel(t, testAttrData, testRoomData+"105,free,FREE,\n", LoadOptions{Logger: logger})
+	if err != nil {
+		t.Fatalf("loading hotel: %s", err.Error())
+	}
+	if got := logger.matching("skipping record 6"); got != 1 {
+		t.Errorf("got %d warnings for the bad record, want 1 (messages: %q)", got, logger.msgs)
+	}
+}
+
+func TestLoadDuplicates(t *testing.T) {
+	roomData := testRoomData + `101,100,FREE,"sea_view,balcony"
+102,90,FREE,balcony
+`
+	for _, skip := range []bool{false, true} {
+		logger := &captureLogger{}
+		h, err := loadTestHotel(t, testAttrData, roomData, LoadOptions{
+			Logger:                  logger,
+			SkipIdenticalDuplicates: skip,
+		})
+		if err != nil {
+			t.Fatalf("loading hotel: %s", err.Error())
+		}
+		want := 2
+		if skip {
+			// only the conflicting duplicate is reported
+			want = 1
+		}
+		if got := logger.matching("duplicate room"); got != want {
+			t.Errorf("skip %t: got %d warnings, want %d (messages: %q)", skip, got, want, logger.msgs)
+		}
+		if got := h.Find(Query{Attributes: []room.Attribute{"balcony"}}); len(got) != 2 || got[1].Price() != 90 {
+			t.Errorf("skip %t: the last duplicate record did not win", skip)
+		}
+	}
+}
+
+func TestLoadAttributes(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		data   string
+		want   []room.Attribute
+	}{
+		{"hash comments", "", "# comment\nbalcony # trailing\n  # indented\n\nminibar\n", []room.Attribute{"balcony", "minibar"}},
+		{"slash comments", "//", "// comment\nbalcony\n# not a comment\n", []room.Attribute{"#", "balcony"}},
+		{"semicolon comments", ";", "; comment\nbalcony ; trailing\n", []room.Attribute{"balcony"}},
+		{"marker inside a word", "#", "sea#view\n", []room.Attribute{"sea#view"}},
+		{"mixed case collapses", "", "Balcony\nBALCONY\nbalcony\n", []room.Attribute{"balcony"}},
+		{"quoted", "", "\"sea view\" # nice\nbalcony two words\n\"\"\n", []room.Attribute{"balcony", "sea view"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := loadTestHotel(t, tt.data, "", LoadOptions{CommentPrefix: tt.prefix})
+			if err != nil {
+				t.Fatalf("loading hotel: %s", err.Error())
+			}
+			if got := h.Attributes(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if _, err := loadTestHotel(t, "balcony\n\"sea view\n", "", LoadOptions{}); err == nil {
+		t.Errorf("expected error for an unterminated quoted attribute")
+	}
+}
+
+func TestLoadAttributesFromReader(t *testing.T) {
+	h := newTestHotel(t, testAttrData, testRoomData)
+	if err := h.LoadAttributes(strings.NewReader("wifi\n# comment\nBalcony\n")); err != nil {
+		t.Fatalf("LoadAttributes: %s", err.Error())
+	}
+	want := []room.Attribute{"balcony", "minibar", "sea_view", "wifi"}
+	if got := h.Attributes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestNormalization(t *testing.T) {
+	h := newTestHotel(t, "Balcony\nSEA_VIEW\n", "101,100,FREE,\"BALCONY,Sea_View\"\n")
+	if got, want := h.Attributes(), []room.Attribute{"balcony", "sea_view"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("declared attributes: got %q, want %q", got, want)
+	}
+	if errs := h.Validate(); len(errs) != 0 {
+		t.Errorf("attributes of the rooms do not match the declared ones: %v", errs)
+	}
+	if got := h.Find(Query{Attributes: []room.Attribute{"Balcony"}}); len(got) != 1 {
+		t.Errorf("Find: got %d rooms, want 1", len(got))
+	}
+	if h.AddAttribute("BALCONY") {
+		t.Errorf("AddAttribute: differently cased attribute declared twice")
+	}
+
+	verbatim, err := loadTestHotel(t, "Balcony\nbalcony\n", "101,100,FREE,Balcony\n", LoadOptions{
+		Normalize: room.Verbatim,
+	})
+	if err != nil {
+		t.Fatalf("loading hotel: %s", err.Error())
+	}
+	if got := verbatim.Attributes(); len(got) != 2 {
+		t.Errorf("verbatim: got %q, want both attributes", got)
+	}
+	if got := verbatim.Find(Query{Attributes: []room.Attribute{"balcony"}}); len(got) != 0 {
+		t.Errorf("verbatim: Find matched a differently cased attribute")
+	}
+}
+
+func TestFindN(t *testing.T) {
+	h := newTestHotel(t, testAttrData, testRoomData)
+	cheap := func(r *room.Room) bool { return r.Price() <= 100 }
+	tests := []struct {
+		name string
+		n    int
+		want []room.Number
+	}{
+		{"fewer than the matches", 2, []room.Number{101, 102}},
+		{"as many as the matches", 3, []room.Number{101, 102, 104}},
+		{"more than the matches", 10, []room.Number{101, 102, 104}},
+		{"none", 0, []room.Number{}},
+	}
+	for _, tt := range tests {
+		if got := numbersOf(h.FindN(tt.n, cheap)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	checked := 0
+	h.FindN(1, func(*room.Room) bool { checked++; return true })
+	if checked != 1 {
+		t.Errorf("search did not stop at the first match (%d rooms checked)", checked)
+	}
+}
+
+func TestCount(t *testing.T) {
+	h := newTestHotel(t, testAttrData, testRoomData)
+	predicates := []RoomPredicate{
+		isFree,
+		func(r *room.Room) bool { return r.HasAttribute("sea_view") },
+		func(r *room.Room) bool { return r.Price() > 1000 },
+		func(*room.Room) bool { return true },
+	}
+	for i, p := range predicates {
+		if got, want := h.Count(p), len(h.FindN(100, p)); got != want {
+			t.Errorf("predicate %d: got %d, want %d", i, got, want)
+		}
+	}
+}
+
+func TestDeterministicOrder(t *testing.T) {
+	h := newTestHotel(t, testAttrData, testRoomData)
+	for i := 0; i < 10; i++ {
+		if got := numbersOf(h.Rooms()); !reflect.DeepEqual(got, h.Numbers()) {
+			t.Fatalf("Rooms and Numbers disagree: %v, %v", got, h.Numbers())
+		}
+		if !reflect.DeepEqual(h.Search("sea"), h.Search("sea")) {
+			t.Fatalf("successive identical queries differ")
+		}
+	}
+	if got, want := h.Numbers(), []room.Number{101, 102, 103, 104}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Numbers: got %v, want %v", got, want)
+	}
+}
+
+func TestAddAttribute(t *testing.T) {
+	h := newTestHotel(t, testAttrData, testRoomData)
+	if !h.AddAttribute("wifi") {
+		t.Errorf("new attribute not added")
+	}
+	if h.AddAttribute("wifi") || h.AddAttribute("balcony") {
+		t.Errorf("declared attribute added again")
+	}
+	want := []room.Attribute{"balcony", "minibar", "sea_view", "wifi"}
+	if got := h.Attributes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestAddAttributeToRooms(t *testing.T) {
+	h := newTestHotel(t, testAttrData, testRoomData)
+	missing := h.AddAttributeToRooms([]room.Number{101, 999, 104, 998}, "MiniBar")
+	if want := []room.Number{999, 998}; !reflect.DeepEqual(missing, want) {
+		t.Errorf("missing: got %v, want %v", missing, want)
+	}
+	got := numbersOf(h.Find(Query{Attributes: []room.Attribute{"minibar"}}))
+	if want := []room.Number{101, 103, 104}; !reflect.DeepEqual(got, want) {
+		t.Errorf("rooms with the attribute: got %v, want %v", got, want)
+	}
+}
+
+func TestGroupByPriceBand(t *testing.T) {
+	h := newTestHotel(t, testAttrData, testRoomData)
+	got := make(map[uint][]room.Number)
+	for band, rooms := range h.GroupByPriceBand(50) {
+		got[band] = numbersOf(rooms)
+	}
+	want := map[uint][]room.Number{50: {102, 104}, 100: {101, 103}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := len(h.GroupByPriceBand(0)); got != 4 {
+		t.Errorf("band size 0: got %d bands, want 4", got)
+	}
+}
+
+func TestLenAndFreeCount(t *testing.T) {
+	h := newTestHotel(t, testAttrData, testRoomData)
+	check := func(step string, wantLen, wantFree uint) {
+		t.Helper()
+		if got := h.Len(); got != wantLen {
+			t.Errorf("%s: Len got %d, want %d", step, got, wantLen)
+		}
+		if got := h.FreeCount(); got != wantFree {
+			t.Errorf("%s: FreeCount got %d, want %d", step, got, wantFree)
+		}
+	}
+	check("loaded", 4, 2)
+	err := h.LoadRoomsJSON(strings.NewReader(
+		`[{"id": 105, "price": 70, "state": "FREE", "attributes": []}]`,
+	), true)
+	if err != nil {
+		t.Fatalf("LoadRoomsJSON: %s", err.Error())
+	}
+	check("added", 5, 3)
+	h.SoftDeleteRoom(101)
+	check("deleted", 4, 2)
+	h.RestoreRoom(101)
+	check("restored", 5, 3)
+}
+
+func TestClone(t *testing.T) {
+	h := newTestHotel(t, testAttrData, testRoomData)
+	want := h.Fingerprint()
+	clone := h.Clone()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for _, r := range clone.Rooms() {
+			r.SetPrice(r.Price() * 2)
+			r.AddAttribute("minibar")
+		}
+		clone.AddAttribute("wifi")
+		clone.SoftDeleteRoom(101)
+	}()
+	go func() {
+		defer wg.Done()
+		h.Find(Query{State: room.StateFree})
+		h.Fingerprint()
+	}()
+	wg.Wait()
+	if got := h.Fingerprint(); got != want {
+		t.Errorf("modifying the clone changed the original")
+	}
+	if clone.Fingerprint() == want {
+		t.Errorf("clone not modified")
+	}
+}
+
+func TestNearestToPrice(t *testing.T) {
+	h := newTestHotel(t, testAttrData, testRoomData)
+	tests := []struct {
+		name   string
+		target uint
+		attrs  []room.Attribute
+		want   room.Number
+		ok     bool
+	}{
+		{"exact", 80, nil, 102, true},
+		{"above every price", 500, nil, 103, true},
+		{"below every price", 10, nil, 104, true},
+		{"tie broken by lower price", 70, nil, 104, true},
+		{"with attributes", 90, []room.Attribute{"sea_view"}, 101, true},
+		{"no match", 90, []room.Attribute{"jacuzzi"}, 0, false},
+	}
+	for _, tt := range tests {
+		r, ok := h.NearestToPrice(tt.target, tt.attrs)
+		if ok != tt.ok || (ok && r.ID() != tt.want) {
+			t.Errorf("%s: got %v, %t, want %d, %t", tt.name, r, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestWriteJSONL(t *testing.T) {
+	h := newTestHotel(t, testAttrData, testRoomData)
+	var buf bytes.Buffer
+	if err := h.WriteJSONL(&buf); err != nil {
+		t.Fatalf("WriteJSONL: %s", err.Error())
+	}
+	var got []room.Number
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		r := &room.Room{}
+		if err := json.Unmarshal(scanner.Bytes(), r); err != nil {
+			t.Fatalf("decoding %q: %s", scanner.Text(), err.Error())
+		}
+		if !r.Equal(h.Find(Query{})[len(got)]) {
+			t.Errorf("line %d: decoded room differs", len(got)+1)
+		}
+		got = append(got, r.ID())
+	}
+	if want := h.Numbers(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got rooms %v, want %v", got, want)
+	}
+}
+
+func TestLoadRoomsJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		strict bool
+		ok     bool
+		want   map[room.Number]uint
+	}{
+		{
+			"valid",
+			`[{"id": 105, "price": 70, "state": "FREE", "attributes": ["balcony"]}]`,
+			true, true,
+			map[room.Number]uint{101: 100, 105: 70},
+		},
+		{
+			"duplicate id",
+			`[{"id": 101, "price": 110, "state": "FREE"}, {"id": 101, "price": 130, "state": "FREE"}]`,
+			true, true,
+			map[room.Number]uint{101: 130},
+		},
+		{
+			"malformed object, strict",
+			`[{"id": 105, "price": 70, "state": "FREE"}, {"id": 106, "price": 70, "state": "CLEANING"}]`,
+			true, false,
+			map[room.Number]uint{101: 100},
+		},
+		{
+			"malformed object, lenient",
+			`[{"id": 105, "price": 70, "state": "FREE"}, {"id": 106, "price": "cheap", "state": "FREE"}]`,
+			false, true,
+			map[room.Number]uint{105: 70},
+		},
+		{"not an array", `{"id": 105}`, false, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHotel(t, testAttrData, testRoomData)
+			before := h.Fingerprint()
+			err := h.LoadRoomsJSON(strings.NewReader(tt.data), tt.strict)
+			if (err == nil) != tt.ok {
+				t.Fatalf("got error %v, want ok: %t", err, tt.ok)
+			}
+			if !tt.ok && h.Fingerprint() != before {
+				t.Errorf("hotel changed by a failed load")
+			}
+			for num, price := range tt.want {
+				if r := h.FindN(1, func(r *room.Room) bool { return r.ID() == num }); len(r) != 1 || r[0].Price() != price {
+					t.Errorf("room %d: want price %d, got %v", num, price, r)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadRoomsJSONDuplicates(t *testing.T) {
+	data := `[
+		{"id": 105, "price": 70, "state": "FREE", "attributes": ["balcony"]},
+		{"id": 105, "price": 70, "state": "FREE", "attributes": ["Balcony"]},
+		{"id": 106, "price": 70, "state": "FREE"},
+		{"id": 106, "price": 90, "state": "FREE"}
+	]`
+	for _, skip := range []bool{false, true} {
+		h := newTestHotel(t, testAttrData, testRoomData)
+		logger := &captureLogger{}
+		h.SetLogger(logger)
+		err := h.LoadRoomsJSONWithOptions(strings.NewReader(data), LoadOptions{
+			Strictness:              Strict,
+			SkipIdenticalDuplicates: skip,
+		})
+		if err != nil {
+			t.Fatalf("skip %t: LoadRoomsJSONWithOptions: %s", skip, err.Error())
+		}
+		want := 2
+		if skip {
+			// only the conflicting duplicate is reported
+			want = 1
+		}
+		if got := logger.matching("duplicate room"); got != want {
+			t.Errorf("skip %t: got %d warnings, want %d (messages: %q)", skip, got, want, logger.msgs)
+		}
+		if got := h.FindN(1, func(r *room.Room) bool { return r.ID() == 106 }); len(got) != 1 || got[0].Price() != 90 {
+			t.Errorf("skip %t: the last duplicate object did not win", skip)
+		}
+	}
+}
+
+func TestForEachRoom(t *testing.T) {
+	h := newTestHotel(t, testAttrData, testRoomData)
+	var total uint
+	h.ForEachRoom(func(r *room.Room) bool {
+		total += r.Price()
+		return true
+	})
+	if total != 360 {
+		t.Errorf("total price: got %d, want 360", total)
+	}
+	var visited []room.Number
+	h.ForEachRoom(func(r *room.Room) bool {
+		visited = append(visited, r.ID())
+		return len(visited) < 2
+	})
+	if want := []room.Number{101, 102}; !reflect.DeepEqual(visited, want) {
+		t.Errorf("early stop: visited %v, want %v", visited, want)
+	}
+}
+
+func TestRank(t *testing.T) {
+	h := newTestHotel(t, testAttrData, testRoomData)
+	tests := []struct {
+		name    string
+		weights map[room.Attribute]float64
+		state   room.State
+		want    []room.Number
+	}{
+		{"by score", map[room.Attribute]float64{"balcony": 1, "sea_view": 2}, room.StateFree, []room.Number{101, 102}},
+		{"ties by price", map[room.Attribute]float64{"minibar": 1}, room.StateFree, []room.Number{102, 101}},
+		{"negative weights", map[room.Attribute]float64{"sea_view": -1}, room.StateFree, []room.Number{102, 101}},
+		{"other state", map[room.Attribute]float64{"balcony": 1}, room.StateOccupied, []room.Number{103}},
+	}
+	for _, tt := range tests {
+		if got := numbersOf(h.Rank(tt.weights, tt.state)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRoomsWithoutAttribute(t *testing.T) {
+	h := newTestHotel(t, testAttrData, testRoomData)
+	tests := []struct {
+		attr room.Attribute
+		want []room.Number
+	}{
+		{"balcony", []room.Number{103, 104}},
+		{"sea_view", []room.Number{102, 104}},
+		{"jacuzzi", []room.Number{101, 102, 103, 104}},
+	}
+	for _, tt := range tests {
+		if got := numbersOf(h.RoomsWithoutAttribute(tt.attr)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.attr, got, tt.want)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	h := newTestHotel(t, testAttrData, testRoomData)
+	tests := []struct {
+		q    string
+		want []room.Number
+	}{
+		{"10", []room.Number{101, 102, 103, 104}},
+		{"103", []room.Number{103}},
+		{"sea", []room.Number{101, 103}},
+		{" BAL ", []room.Number{101, 102}},
+		{"jacuzzi", []room.Number{}},
+		{"", []room.Number{101, 102, 103, 104}},
+	}
+	for _, tt := range tests {
+		if got := numbersOf(h.Search(tt.q)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestUnusedAttributes(t *testing.T) {
+	h := newTestHotel(t, "jacuzzi\n"+testAttrData+"wifi\n", testRoomData)
+	want := []room.Attribute{"jacuzzi", "wifi"}
+	if got := h.UnusedAttributes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRoomsByState(t *testing.T) {
+	h := newTestHotel(t, testAttrData, testRoomData)
+	h.Rooms()[3].SetState(room.StateFree)
+	got := make(map[room.State][]room.Number)
+	for state, rooms := range h.RoomsByState() {
+		if rooms == nil {
+			t.Errorf("%s: nil group", state)
+		}
+		got[state] = numbersOf(rooms)
+	}
+	want := map[room.State][]room.Number{
+		room.StateFree:        {101, 102, 104},
+		room.StateOccupied:    {103},
+		room.StateUnavailable: {},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestAttributePremium(t *testing.T) {
+	h := newTestHotel(t, testAttrData, testRoomData)
+	if premium, ok := h.AttributePremium("sea_view"); !ok || premium != 40 {
+		t.Errorf("sea_view: got %g, %t, want 40, true", premium, ok)
+	}
+	if premium, ok := h.AttributePremium("jacuzzi"); ok || premium != 0 {
+		t.Errorf("jacuzzi: got %g, %t, want 0, false", premium, ok)
+	}
+}
+
+func TestRandomFreeRoom(t *testing.T) {
+	h := newTestHotel(t, testAttrData, testRoomData)
+	for seed := int64(0); seed < 10; seed++ {
+		a, ok := h.RandomFreeRoom(rand.New(rand.NewSource(seed)))
+		b, _ := h.RandomFreeRoom(rand.New(rand.NewSource(seed)))
+		if !ok || a != b || a.State() != room.StateFree {
+			t.Errorf("seed %d: got %v and %v", seed, a, b)
+		}
+	}
+	h.SetStateWhere(isFree, room.StateOccupied)
+	if r, ok := h.RandomFreeRoom(rand.New(rand.NewSource(1))); ok {
+		t.Errorf("no free room: got %v", r)
+	}
+}
+
+func TestSetStateWhere(t *testing.T) {
+	h := newTestHotel(t, testAttrData, testRoomData)
+	freeWithBalcony := func(r *room.Room) bool {
+		return r.State() == room.StateFree && r.HasAttribute("balcony")
+	}
+	if got := h.SetStateWhere(freeWithBalcony, room.StateUnavailable); got != 2 {
+		t.Errorf("got %d changed, want 2", got)
+	}
+	got := numbersOf(h.Find(Query{State: room.StateUnavailable}))
+	if want := []room.Number{101, 102, 104}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unavailable rooms: got %v, want %v", got, want)
+	}
+	if got := h.SetStateWhere(func(*room.Room) bool { return true }, room.StateUnavailable); got != 1 {
+		t.Errorf("rooms already in the state counted: got %d changed, want 1", got)
+	}
+	if got := h.SetStateWhere(func(*room.Room) bool { return true }, "CLEANING"); got != 0 {
+		t.Errorf("invalid state: got %d changed", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	h := newTestHotel(t, testAttrData, testRoomData)
+	if errs := h.Validate(); len(errs) != 0 {
+		t.Fatalf("valid hotel: got %v", errs)
+	}
+	// corrupt the hotel
+	h.rooms[105] = room.NewRoom(106)
+	misfiled, _ := room.NewValidRoom(107, 10, room.StateFree, []room.Attribute{"jacuzzi"})
+	h.rooms[107] = misfiled
+	h.rooms[101].AddAttribute("wifi")
+	want := []string{
+		"room 106: stored under number 105",
+		"room 105: unrecognized state",
+		"room 105: price must be positive",
+		"room 101: undeclared attribute 'wifi'",
+		"room 107: undeclared attribute 'jacuzzi'",
+	}
+	errs := h.Validate()
+	if len(errs) != len(want) {
+		t.Errorf("got %d errors, want %d: %v", len(errs), len(want), errs)
+	}
+	for _, w := range want {
+		found := false
+		for _, err := range errs {
+			found = found || strings.Contains(err.Error(), w)
+		}
+		if !found {
+			t.Errorf("missing error %q", w)
+		}
+	}
+}
+
+func TestValidateKeyedAttributes(t *testing.T) {
+	h := newTestHotel(t, "view\nbeds=2\nbalcony\n", `room_number,price,state,attributes
+101,100,FREE,"view=sea,beds=1"
+102,80,FREE,"view=city,balcony"
+103,90,FREE,"floor=3,balcony=large"
+`)
+	want := []string{"room 103: undeclared attribute 'floor=3'"}
+	var got []string
+	for _, err := range h.Validate() {
+		got = append(got, err.Error())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	h.AddAttribute("minibar")
+	h.AddAttribute("floor")
+	if got, want := h.UnusedAttributes(), []room.Attribute{"minibar"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("UnusedAttributes: got %q, want %q", got, want)
+	}
+}
+
+func TestRenameAttribute(t *testing.T) {
+	h := newTestHotel(t, testAttrData, testRoomData)
+	changed, err := h.RenameAttribute("sea_view", "Ocean_View")
+	if err != nil || changed != 2 {
+		t.Errorf("rename: got %d, %v, want 2, nil", changed, err)
+	}
+	if got, want := h.Attributes(), []room.Attribute{"balcony", "minibar", "ocean_view"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("declared attributes: got %q, want %q", got, want)
+	}
+	if got := numbersOf(h.Find(Query{Attributes: []room.Attribute{"ocean_view"}})); !reflect.DeepEqual(got, []room.Number{101, 103}) {
+		t.Errorf("renamed rooms: got %v", got)
+	}
+	before := h.Fingerprint()
+	if _, err := h.RenameAttribute("balcony", "minibar"); err == nil {
+		t.Errorf("collision: expected error")
+	}
+	if h.Fingerprint() != before {
+		t.Errorf("collision: hotel changed")
+	}
+}
+
+func TestNightlyValue(t *testing.T) {
+	h := newTestHotel(t, testAttrData, testRoomData)
+	if got := h.TotalNightlyValue(); got != 360 {
+		t.Errorf("TotalNightlyValue: got %d, want 360", got)
+	}
+	if got := h.FreeNightlyValue(); got != 180 {
+		t.Errorf("FreeNightlyValue: got %d, want 180", got)
+	}
+	h.Rooms()[0].SetPrice(^uint(0))
+	h.Rooms()[1].SetPrice(^uint(0))
+	if got := h.TotalNightlyValue(); got != ^uint(0) {
+		t.Errorf("TotalNightlyValue: got %d, want the largest uint", got)
+	}
+}
+
+func TestHas(t *testing.T) {
+	h := newTestHotel(t, testAttrData, testRoomData)
+	if !h.Has(101) || h.Has(999) {
+		t.Errorf("wrong membership")
+	}
+	h.SoftDeleteRoom(101)
+	if h.Has(101) {
+		t.Errorf("deleted room present")
+	}
+}
+
+func TestSoftDeleteRoom(t *testing.T) {
+	h := newTestHotel(t, testAttrData, testRoomData)
+	if !h.SoftDeleteRoom(101) || h.SoftDeleteRoom(999) {
+		t.Errorf("SoftDeleteRoom: wrong result")
+	}
+	if got := numbersOf(h.Find(Query{State: room.StateFree})); !reflect.DeepEqual(got, []room.Number{102}) {
+		t.Errorf("Find: got %v, want the deleted room skipped", got)
+	}
+	if got := len(h.RoomsIncludingDeleted()); got != 4 {
+		t.Errorf("RoomsIncludingDeleted: got %d rooms, want 4", got)
+	}
+	if !h.RestoreRoom(101) {
+		t.Errorf("RestoreRoom: room not found")
+	}
+	if got := numbersOf(h.Find(Query{State: room.StateFree})); !reflect.DeepEqual(got, []room.Number{101, 102}) {
+		t.Errorf("Find: got %v, want the restored room", got)
+	}
+}
+
+func TestSoftDeletedRoomIsMissing(t *testing.T) {
+	h := newTestHotel(t, testAttrData, testRoomData)
+	h.SoftDeleteRoom(102)
+	if h.Has(102) {
+		t.Errorf("Has: deleted room present")
+	}
+	if h.SoftDeleteRoom(102) {
+		t.Errorf("SoftDeleteRoom: deleted room deleted again")
+	}
+	if missing := h.AddAttributeToRooms([]room.Number{101, 102}, "minibar"); !reflect.DeepEqual(missing, []room.Number{102}) {
+		t.Errorf("AddAttributeToRooms: got missing %v, want [102]", missing)
+	}
+	if r, ok := h.SuggestUpgrade(102); ok {
+		t.Errorf("SuggestUpgrade: got %v from a deleted room", r)
+	}
+	missing, err := h.ApplyPriceSheet(strings.NewReader("101,110\n102,90\n"))
+	if err != nil || !reflect.DeepEqual(missing, []room.Number{102}) {
+		t.Errorf("ApplyPriceSheet: got missing %v (err: %v), want [102]", missing, err)
+	}
+	if !h.RestoreRoom(102) {
+		t.Fatalf("RestoreRoom: deleted room not found")
+	}
+	r := h.Find(Query{Attributes: []room.Attribute{"balcony"}})[1]
+	if r.ID() != 102 || r.HasAttribute("minibar") || r.Price() != 80 {
+		t.Errorf("deleted room was modified: %v", r)
+	}
+}
+
+func TestSuggestUpgrade(t *testing.T) {
+	h := newTestHotel(t, testAttrData, testRoomData+"105,90,FREE,\"balcony,minibar\"\n")
+	tests := []struct {
+		from room.Number
+		want room.Number
+		ok   bool
+	}{
+		// 101 and 105 are both upgrades - 105 is cheaper
+		{102, 105, true},
+		{101, 0, false},
+		{104, 102, true},
+		{999, 0, false},
+	}
+	for _, tt := range tests {
+		r, ok := h.SuggestUpgrade(tt.from)
+		if ok != tt.ok || (ok && r.ID() != tt.want) {
+			t.Errorf("from %d: got %v, %t, want %d, %t", tt.from, r, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	h := newTestHotel(t, testAttrData, testRoomData)
+	lines := strings.Split(strings.TrimSpace(testRoomData), "\n")
+	reordered := strings.Join([]string{lines[0], lines[3], lines[1], lines[4], lines[2]}, "\n")
+	attrsReordered := "minibar\nsea_view\nbalcony\n"
+	if got := newTestHotel(t, attrsReordered, reordered).Fingerprint(); got != h.Fingerprint() {
+		t.Errorf("reordered data: fingerprint differs")
+	}
+	if h.Clone().Fingerprint() != h.Fingerprint() {
+		t.Errorf("clone: fingerprint differs")
+	}
+	changes := []func(h *Hotel){
+		func(h *Hotel) { h.Rooms()[0].SetPrice(101) },
+		func(h *Hotel) { h.Rooms()[0].SetState(room.StateOccupied) },
+		func(h *Hotel) { h.Rooms()[0].AddAttribute("minibar") },
+		func(h *Hotel) { h.AddAttribute("wifi") },
+	}
+	for i, change := range changes {
+		c := h.Clone()
+		change(c)
+		if c.Fingerprint() == h.Fingerprint() {
+			t.Errorf("change %d: fingerprint unchanged", i)
+		}
+	}
+}
+
+func TestWriteCSVColumns(t *testing.T) {
+	h := newTestHotel(t, testAttrData, testRoomData)
+	var buf bytes.Buffer
+	if err := h.WriteCSVColumns(&buf, []string{"id", "state"}); err != nil {
+		t.Fatalf("WriteCSVColumns: %s", err.Error())
+	}
+	want := "id,state\n101,FREE\n102,FREE\n103,OCCUPIED\n104,UNAVAILABLE\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	buf.Reset()
+	if err := h.WriteCSVColumns(&buf, []string{"id", "floor"}); err == nil || buf.Len() != 0 {
+		t.Errorf("unknown column: got error %v, wrote %q", err, buf.String())
+	}
+}
+
+func TestScalePrices(t *testing.T) {
+	tests := []struct {
+		name    string
+		factor  float64
+		changed int
+		want    []uint
+	}{
+		{"increase", 1.1, 4, []uint{110, 88, 132, 66}},
+		{"decrease", 0.5, 4, []uint{50, 40, 60, 30}},
+		{"clamped", 0.001, 4, []uint{1, 1, 1, 1}},
+		{"unchanged", 1, 0, []uint{100, 80, 120, 60}},
+		{"invalid", math.NaN(), 0, []uint{100, 80, 120, 60}},
+	}
+	for _, tt := range tests {
+		h := newTestHotel(t, testAttrData, testRoomData)
+		if got := h.ScalePrices(tt.factor); got != tt.changed {
+			t.Errorf("%s: got %d changed, want %d", tt.name, got, tt.changed)
+		}
+		var prices []uint
+		for _, r := range h.Rooms() {
+			prices = append(prices, r.Price())
+		}
+		if !reflect.DeepEqual(prices, tt.want) {
+			t.Errorf("%s: got prices %v, want %v", tt.name, prices, tt.want)
+		}
+	}
+}
+
+func TestBestMatch(t *testing.T) {
+	h := newTestHotel(t, testAttrData, testRoomData)
+	tests := []struct {
+		name  string
+		attrs []room.Attribute
+		state room.State
+		want  room.Number
+		count int
+	}{
+		{"full match", []room.Attribute{"balcony", "sea_view"}, room.StateFree, 101, 2},
+		{"partial match", []room.Attribute{"sea_view", "minibar"}, room.StateFree, 101, 1},
+		{"no attribute matches", []room.Attribute{"jacuzzi"}, room.StateFree, 102, 0},
+		{"no room in state", nil, "CLEANING", 0, 0},
+	}
+	for _, tt := range tests {
+		r, count := h.BestMatch(tt.attrs, tt.state)
+		if count != tt.count || (r == nil) != (tt.want == 0) || (r != nil && r.ID() != tt.want) {
+			t.Errorf("%s: got %v, %d, want %d, %d", tt.name, r, count, tt.want, tt.count)
+		}
+	}
+}
+
+func TestApplyPriceSheet(t *testing.T) {
+	tests := []struct {
+		name    string
+		sheet   string
+		strict  bool
+		ok      bool
+		missing []room.Number
+		want    []uint
+	}{
+		{"valid", "number,price\n101,150\n104,65\n", true, true, nil, []uint{150, 80, 120, 65}},
+		{"unknown number", "101,150\n999,10\n", true, true, []room.Number{999}, []uint{150, 80, 120, 60}},
+		{"malformed row", "101,150\n102,cheap\n", false, true, nil, []uint{150, 80, 120, 60}},
+		{"malformed row, strict", "101,150\n102,cheap\n", true, false, nil, []uint{100, 80, 120, 60}},
+		{"zero price", "101,150\n102,0\n", false, true, nil, []uint{150, 80, 120, 60}},
+		{"zero price, strict", "101,150\n102,0\n", true, false, nil, []uint{100, 80, 120, 60}},
+		{"first row, wrong field count, strict", "101\n", true, false, nil, []uint{100, 80, 120, 60}},
+		{"first row, bad price, strict", "101,cheap\n102,90\n", true, false, nil, []uint{100, 80, 120, 60}},
+		{"first row, wrong field count", "101\n102,90\n", false, true, nil, []uint{100, 90, 120, 60}},
+		{"wrong field count, strict", "101,150\n102\n", true, false, nil, []uint{100, 80, 120, 60}},
+	}
+	for _, tt := range tests {
+		h := newTestHotel(t, testAttrData, testRoomData)
+		apply := h.ApplyPriceSheet
+		if tt.strict {
+			apply = h.ApplyPriceSheetStrict
+		}
+		missing, err := apply(strings.NewReader(tt.sheet))
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: got error %v, want ok: %t", tt.name, err, tt.ok)
+		}
+		if !reflect.DeepEqual(missing, tt.missing) {
+			t.Errorf("%s: got missing %v, want %v", tt.name, missing, tt.missing)
+		}
+		var prices []uint
+		for _, r := range h.Rooms() {
+			prices = append(prices, r.Price())
+		}
+		if !reflect.DeepEqual(prices, tt.want) {
+			t.Errorf("%s: got prices %v, want %v", tt.name, prices, tt.want)
+		}
+	}
+}
+
+func TestModifiedSince(t *testing.T) {
+	prev := date.Now
+	defer func() { date.Now = prev }()
+	setToday := func(day int) {
+		date.Now = func() time.Time { return time.Date(2021, 3, day, 12, 0, 0, 0, time.UTC) }
+	}
+	h := newTestHotel(t, testAttrData, testRoomData)
+	setToday(1)
+	h.Rooms()[0].SetPrice(110)
+	setToday(5)
+	h.Rooms()[1].SetState(room.StateOccupied)
+	h.Rooms()[3].AddAttribute("minibar")
+	tests := []struct {
+		day  uint
+		want []room.Number
+	}{
+		{1, []room.Number{101, 102, 104}},
+		{3, []room.Number{102, 104}},
+		{5, []room.Number{102, 104}},
+		{6, []room.Number{}},
+	}
+	for _, tt := range tests {
+		d := &date.Date{Day: tt.day, Month: 3, Year: 2021}
+		if got := numbersOf(h.ModifiedSince(d)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("since %v: got %v, want %v", d, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package hotel
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/navaz-alani/hotel/room"
+)
+
+// `legacyHeaderHints` lists, for each part of a record, the phrases which
+// identify its column in the header of a legacy CSV file, most specific first.
+// A phrase matches a header cell if its words appear, consecutively, amongst
+// the words of the cell (see `headerWords`).
+var legacyHeaderHints = [...]struct {
+	entry int
+	hints []string
+}{
+	{room.EntryID, []string{
+		"room number", "room no", "room #", "room id", "number", "no", "#", "id", "room",
+	}},
+	{room.EntryPrice, []string{"price", "rate", "cost", "tariff"}},
+	{room.EntryState, []string{"state", "status"}},
+	{room.EntryAttributes, []string{
+		"attributes", "attribute", "amenities", "amenity", "features", "feature", "tags", "tag",
+	}},
+}
+
+// `headerWords` splits the header cell `cell` into its lower-cased words,
+// which are separated by any characters other than letters, digits and '#' (a
+// word of its own).
+func headerWords(cell string) []string {
+	var words []string
+	for _, field := range strings.FieldsFunc(strings.ToLower(cell), func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '#'
+	}) {
+		// "#" is split from the words it is attached to (e.g. "room#")
+		for i, part := range strings.Split(field, "#") {
+			if i > 0 {
+				words = append(words, "#")
+			}
+			if part != "" {
+				words = append(words, part)
+			}
+		}
+	}
+	return words
+}
+
+// `containsPhrase` returns whether the words of `phrase` appear consecutively
+// in `words`.
+func containsPhrase(words []string, phrase string) bool {
+	hint := strings.Fields(phrase)
+	for i := 0; i+len(hint) <= len(words); i++ {
+		match := true
+		for j, w := range hint {
+			if words[i+j] != w {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// `legacyColumnsFromHeader` is like `room.ColumnsFromHeader`, but matches the
+// header cells heuristically, using `legacyHeaderHints`. Exact header names
+// take precedence. Otherwise, the parts of a record are mapped in order, each
+// to the first unclaimed cell matching its most specific hint - so "Room No"
+// identifies the room number in preference to "Room Type", and "room_rate"
+// identifies the room number, not the price.
+func legacyColumnsFromHeader(header []string) (room.Columns, bool) {
+	if cols, ok := room.ColumnsFromHeader(header); ok {
+		return cols, true
+	}
+	words := make([][]string, len(header))
+	for i, cell := range header {
+		words[i] = headerWords(cell)
+	}
+	var cols room.Columns
+	found := make(map[int]bool)
+	claimed := make(map[int]bool)
+	for _, field := range legacyHeaderHints {
+		for _, hint := range field.hints {
+			for i := range header {
+				if claimed[i] || found[field.entry] || !containsPhrase(words[i], hint) {
+					continue
+				}
+				cols[field.entry] = i
+				found[field.entry] = true
+				claimed[i] = true
+			}
+		}
+	}
+	return cols, len(found) == len(cols)
+}
+
+// `NewHotelFromLegacyCSV` creates a new `Hotel` from the room data in `r`, a
+// CSV file from a legacy system. The columns are mapped from the header by
+// matching common names heuristically (e.g. "Room No" or "Rate"), falling back
+// to the positional order of a record when they cannot all be identified.
+// There is no attributes data file: the declared attributes are those found on
+// the rooms. Bad records are handled as in `NewHotelFromData`, except that an
+// error is returned if every record is bad - the columns were most likely
+// misidentified.
+func NewHotelFromLegacyCSV(r io.Reader, strict bool) (*Hotel, error) {
+	opts := LoadOptions{
+		Strictness:        Lenient,
+		columnsFromHeader: legacyColumnsFromHeader,
+		rejectAllBad:      true,
+	}
+	if strict {
+		opts.Strictness = Strict
+	}
+	opts = opts.withDefaults()
+	hotel := newHotel()
+	if err := hotel.readRooms(r, opts); err != nil {
+		return nil, fmt.Errorf("legacy %s", err.Error())
+	}
+	declared := room.NewAttributeSet()
+	for _, rm := range hotel.rooms {
+		for _, attr := range rm.Attributes() {
+			declared.Add(attr)
+		}
+	}
+	hotel.roomAttrs = declared.Slice()
+	return hotel, nil
+}
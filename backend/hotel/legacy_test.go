@@ -0,0 +1,89 @@
+package hotel
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/navaz-alani/hotel/room"
+)
+
+func TestNewHotelFromLegacyCSV(t *testing.T) {
+	records := strings.SplitN(testRoomData, "\n", 2)[1]
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"exact header", testRoomData},
+		{"rate and status", "Room #,Nightly Rate,Current Status,Tags\n" + records},
+		{"cost", "No.,Cost,State,Attributes\n" + records},
+		{"features", "ID,Price,State,Features\n" + records},
+		{"ambiguous room", "Room Type,Room No,Rate,Status,Amenities\n" + strings.Join([]string{
+			`double,101,100,FREE,"balcony,sea_view"`,
+			"single,102,80,FREE,balcony",
+			`suite,103,120,OCCUPIED,"minibar,sea_view"`,
+			"single,104,60,UNAVAILABLE,",
+		}, "\n")},
+		{"attached #", "Notes,Room#,Paid Rate,Validity Status,Tags\n" + strings.Join([]string{
+			`n/a,101,100,FREE,"balcony,sea_view"`,
+			"n/a,102,80,FREE,balcony",
+			`n/a,103,120,OCCUPIED,"minibar,sea_view"`,
+			"n/a,104,60,UNAVAILABLE,",
+		}, "\n")},
+		{
+			"other order",
+			`Amenities,Status,Room Number,Tariff
+"balcony,sea_view",FREE,101,100
+balcony,FREE,102,80
+"minibar,sea_view",OCCUPIED,103,120
+,UNAVAILABLE,104,60
+`,
+		},
+		{"no header", records},
+	}
+	want := newTestHotel(t, testAttrData, testRoomData).Fingerprint()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := NewHotelFromLegacyCSV(strings.NewReader(tt.data), true)
+			if err != nil {
+				t.Fatalf("loading hotel: %s", err.Error())
+			}
+			if got := h.Fingerprint(); got != want {
+				t.Errorf("loaded different rooms: %v", h.Rooms())
+			}
+			wantAttrs := []room.Attribute{"balcony", "minibar", "sea_view"}
+			if got := h.Attributes(); !reflect.DeepEqual(got, wantAttrs) {
+				t.Errorf("declared attributes: got %q, want %q", got, wantAttrs)
+			}
+		})
+	}
+}
+
+func TestLegacyAllRecordsBad(t *testing.T) {
+	data := "Code,Rate,Status,Tags\nA1,100,FREE,balcony\nA2,80,FREE,\n"
+	if h, err := NewHotelFromLegacyCSV(strings.NewReader(data), false); err == nil {
+		t.Errorf("expected error, got %d rooms", h.Len())
+	}
+	if _, err := NewHotelFromLegacyCSV(strings.NewReader(""), false); err != nil {
+		t.Errorf("empty file: unexpected error %s", err.Error())
+	}
+}
+
+func TestHeaderWords(t *testing.T) {
+	tests := []struct {
+		cell string
+		want []string
+	}{
+		{"Room No.", []string{"room", "no"}},
+		{" room_number ", []string{"room", "number"}},
+		{"Room#", []string{"room", "#"}},
+		{"#", []string{"#"}},
+		{"Notes", []string{"notes"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := headerWords(tt.cell); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %q, want %q", tt.cell, got, tt.want)
+		}
+	}
+}
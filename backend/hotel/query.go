@@ -0,0 +1,152 @@
+package hotel
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/navaz-alani/hotel/room"
+)
+
+// `Query` describes the rooms to be found by `Hotel.Find`.
+type Query struct {
+	// `Attributes` which a room must have.
+	Attributes []room.Attribute
+	// `State` which a room must be in. Rooms in any state match if empty.
+	State room.State
+	// `MaxPrice` is the highest price of a matching room. Rooms at any price
+	// match if 0.
+	MaxPrice uint
+}
+
+// `key` returns a string which uniquely identifies the query, regardless of
+// the order of its attributes.
+func (q Query) key() string {
+	attrs := make([]string, len(q.Attributes))
+	for i, attr := range q.Attributes {
+		attrs[i] = string(attr)
+	}
+	sort.Strings(attrs)
+	return fmt.Sprintf("%q|%s|%d", attrs, q.State, q.MaxPrice)
+}
+
+// `matches` returns whether the room `r` satisfies the query.
+func (q Query) matches(r *room.Room) bool {
+	if q.State != "" && r.State() != q.State {
+		return false
+	}
+	if q.MaxPrice != 0 && r.Price() > q.MaxPrice {
+		return false
+	}
+	return r.Satisfies(q.Attributes)
+}
+
+// `maxCachedQueries` is the number of distinct queries whose results are
+// cached at once. Once reached, the cache is emptied before a new query is
+// cached.
+const maxCachedQueries = 64
+
+// `stamp` identifies a state of a hotel and its rooms, for the purpose of
+// caching query results.
+type stamp struct {
+	// version is the version of the hotel (see `Hotel.version`)
+	version uint64
+	// changes is the sum of the change counters of the rooms of the hotel -
+	// since the rooms are fixed for a given version, and their counters only
+	// increase, it identifies a state of the rooms for that version
+	changes uint64
+}
+
+// `before` returns whether `s` identifies an earlier state than `t`.
+func (s stamp) before(t stamp) bool {
+	return s.version < t.version || (s.version == t.version && s.changes < t.changes)
+}
+
+// `stamp` returns the current stamp of the hotel. It reads the change counter
+// of every room, without taking their locks.
+//
+// The caller must hold the (read or write) lock of the hotel.
+func (h *Hotel) stamp() stamp {
+	st := stamp{version: atomic.LoadUint64(&h.version)}
+	for _, r := range h.rooms {
+		st.changes += r.Changes()
+	}
+	return st
+}
+
+// `queryCache` memoizes the results of queries. The results are only valid
+// for the state of the hotel, identified by a `stamp`, at which they were
+// computed. At most `maxCachedQueries` results are held.
+type queryCache struct {
+	mu      sync.Mutex
+	stamp   stamp
+	results map[string][]*room.Room
+}
+
+// `newQueryCache` returns a pointer to an empty `queryCache`.
+func newQueryCache() *queryCache {
+	return &queryCache{results: make(map[string][]*room.Room)}
+}
+
+// `get` returns the cached results of the query with key `key`, if they were
+// computed at the state `st` of the hotel.
+func (c *queryCache) get(st stamp, key string) ([]*room.Room, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stamp != st {
+		return nil, false
+	}
+	rooms, ok := c.results[key]
+	return rooms, ok
+}
+
+// `put` caches the results, `rooms`, of the query with key `key`, computed at
+// the state `st` of the hotel. Results cached for other states are discarded,
+// unless `st` is the earlier one.
+func (c *queryCache) put(st stamp, key string, rooms []*room.Room) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if st.before(c.stamp) {
+		return
+	}
+	if c.stamp != st || len(c.results) >= maxCachedQueries {
+		c.stamp = st
+		c.results = make(map[string][]*room.Room)
+	}
+	c.results[key] = rooms
+}
+
+// `Find` returns the rooms of the hotel matching the query `q`, in ascending
+// order of room number. The attributes of the query are normalized (see
+// `Hotel.Normalize`).
+//
+// Results are cached until the hotel, or any of its rooms, is next modified -
+// whether through the `Hotel` or directly through a `*room.Room` (see
+// `room.Room.Changes`). Up to `maxCachedQueries` distinct queries are cached.
+// Checking the cache reads the change counter of every room, which is still
+// much cheaper than matching the rooms against the query. It holds the hotel's
+// read lock, reading each room under its own lock in turn.
+func (h *Hotel) Find(q Query) []*room.Room {
+	q.Attributes = h.normalizeAll(q.Attributes)
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	key := q.key()
+	// the stamp is read before the rooms, so that results computed while a
+	// room changes are cached under a stamp which is already stale
+	st := h.stamp()
+	matches, ok := h.cache.get(st, key)
+	if !ok {
+		matches = nil
+		for _, num := range h.sortedNumbers() {
+			if r := h.rooms[num]; q.matches(r) {
+				matches = append(matches, r)
+			}
+		}
+		h.cache.put(st, key, matches)
+	}
+	// copy, so that callers cannot modify the cached results
+	results := make([]*room.Room, len(matches))
+	copy(results, matches)
+	return results
+}
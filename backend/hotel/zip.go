@@ -0,0 +1,81 @@
+package hotel
+
+import (
+	"archive/zip"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Names of the data files in a hotel archive.
+const (
+	ZipAttributesFile = "attributes.txt"
+	ZipRoomsFile      = "rooms.csv"
+)
+
+// `NewHotelFromZip` creates a new `Hotel` from the zip archive at `zipPath`,
+// which contains both data files (see `NewHotelFromData`). The files are found
+// by name - `ZipAttributesFile` and `ZipRoomsFile`, in any directory of the
+// archive - or, failing that, as the only ".txt" and ".csv" files in it.
+func NewHotelFromZip(zipPath string, strict bool) (*Hotel, error) {
+	archive, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, fmt.Errorf("zip load err: %s", err.Error())
+	}
+	defer archive.Close()
+
+	attrFile, err := findZipEntry(archive.File, ZipAttributesFile)
+	if err != nil {
+		return nil, err
+	}
+	roomsFile, err := findZipEntry(archive.File, ZipRoomsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := LoadOptions{Strictness: Lenient}
+	if strict {
+		opts.Strictness = Strict
+	}
+	opts = opts.withDefaults()
+	hotel := newHotel()
+	attrData, err := attrFile.Open()
+	if err != nil {
+		return nil, fmt.Errorf("zip load err (%s): %s", attrFile.Name, err.Error())
+	}
+	defer attrData.Close()
+	if err := hotel.readAttributes(attrData, opts); err != nil {
+		return nil, err
+	}
+	roomData, err := roomsFile.Open()
+	if err != nil {
+		return nil, fmt.Errorf("zip load err (%s): %s", roomsFile.Name, err.Error())
+	}
+	defer roomData.Close()
+	if err := hotel.readRooms(roomData, opts); err != nil {
+		return nil, err
+	}
+	return hotel, nil
+}
+
+// `findZipEntry` returns the file in `files` with the base name `name`. If
+// there is none, the only file with the same extension as `name` is returned
+// instead. An error is returned if neither can be found.
+func findZipEntry(files []*zip.File, name string) (*zip.File, error) {
+	var sameExt []*zip.File
+	for _, f := range files {
+		if strings.HasSuffix(f.Name, "/") { // directory
+			continue
+		}
+		if path.Base(f.Name) == name {
+			return f, nil
+		}
+		if path.Ext(f.Name) == path.Ext(name) {
+			sameExt = append(sameExt, f)
+		}
+	}
+	if len(sameExt) == 1 {
+		return sameExt[0], nil
+	}
+	return nil, fmt.Errorf("zip load err: missing entry '%s'", name)
+}
@@ -0,0 +1,88 @@
+package hotel
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// `writeTestZip` writes an archive holding `files` (names mapped to contents)
+// to a temporary file, and returns its path.
+func writeTestZip(t *testing.T, files map[string]string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "hotel.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("creating archive: %s", err.Error())
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for name, data := range files {
+		entry, err := w.Create(name)
+		if err != nil {
+			t.Fatalf("creating archive entry: %s", err.Error())
+		}
+		if _, err := entry.Write([]byte(data)); err != nil {
+			t.Fatalf("writing archive entry: %s", err.Error())
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("closing archive: %s", err.Error())
+	}
+	return path
+}
+
+func TestNewHotelFromZip(t *testing.T) {
+	want := newTestHotel(t, testAttrData, testRoomData).Fingerprint()
+	tests := []struct {
+		name  string
+		files map[string]string
+		ok    bool
+	}{
+		{"by name", map[string]string{ZipAttributesFile: testAttrData, ZipRoomsFile: testRoomData}, true},
+		{
+			"in a directory",
+			map[string]string{
+				"data/":                     "",
+				"data/" + ZipAttributesFile: testAttrData,
+				"data/" + ZipRoomsFile:      testRoomData,
+			},
+			true,
+		},
+		{
+			"by extension",
+			map[string]string{"amenities.txt": testAttrData, "export.csv": testRoomData, "README.md": "notes"},
+			true,
+		},
+		{
+			"name over extension",
+			map[string]string{
+				ZipAttributesFile: testAttrData,
+				"notes.txt":       "jacuzzi\n",
+				ZipRoomsFile:      testRoomData,
+			},
+			true,
+		},
+		{"missing entry", map[string]string{ZipAttributesFile: testAttrData}, false},
+		{
+			"ambiguous extension",
+			map[string]string{ZipAttributesFile: testAttrData, "a.csv": testRoomData, "b.csv": testRoomData},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := NewHotelFromZip(writeTestZip(t, tt.files), true)
+			if (err == nil) != tt.ok {
+				t.Fatalf("got error %v, want ok: %t", err, tt.ok)
+			}
+			if tt.ok && h.Fingerprint() != want {
+				t.Errorf("loaded different rooms: %v", h.Rooms())
+			}
+		})
+	}
+	if _, err := NewHotelFromZip(filepath.Join(t.TempDir(), "missing.zip"), true); err == nil {
+		t.Errorf("bad path: expected error")
+	}
+}
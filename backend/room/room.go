@@ -1,10 +1,16 @@
 package room
 
 import (
+	"encoding/json"
 	"fmt"
+	"math/bits"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+
+	"github.com/navaz-alani/hotel/date"
 )
 
 // Possible room states.
@@ -20,25 +26,143 @@ const (
 	EntryPrice
 	EntryState
 	EntryAttributes
+
+	numEntries
+)
+
+// Header names of the parts of a record.
+const (
+	HeaderID         = "room_number"
+	HeaderPrice      = "price"
+	HeaderState      = "state"
+	HeaderAttributes = "attributes"
 )
 
+// `Columns` maps each part of a record (`EntryID`, `EntryPrice`, ...) to the
+// index of the column in which it is found.
+type Columns [numEntries]int
+
+// `DefaultColumns` is the positional column order of a record.
+var DefaultColumns = Columns{EntryID, EntryPrice, EntryState, EntryAttributes}
+
+// `ByteOrderMark` is the UTF-8 byte order mark, which spreadsheet applications
+// often prepend to exported files.
+const ByteOrderMark = "\uFEFF"
+
+// `EntryForHeader` returns the part of a record (`EntryID`, `EntryPrice`, ...)
+// named by the header cell `name`, matched case-insensitively and ignoring
+// surrounding whitespace. Besides `HeaderID`, "id" also names `EntryID`. The
+// returned boolean is false if `name` names no part of a record.
+func EntryForHeader(name string) (int, bool) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case HeaderID, "id":
+		return EntryID, true
+	case HeaderPrice:
+		return EntryPrice, true
+	case HeaderState:
+		return EntryState, true
+	case HeaderAttributes:
+		return EntryAttributes, true
+	default:
+		return 0, false
+	}
+}
+
+// `ColumnsFromHeader` returns the `Columns` described by the header record
+// `header`, whose cells are matched (case-insensitively) against the header
+// names of the parts of a record. Any whitespace around the cells is ignored.
+// The returned boolean is false if any part of a record is missing from, or
+// repeated in, the header.
+func ColumnsFromHeader(header []string) (Columns, bool) {
+	var cols Columns
+	found := [numEntries]bool{}
+	for i, cell := range header {
+		entry, ok := EntryForHeader(cell)
+		if !ok {
+			continue
+		}
+		if found[entry] {
+			return cols, false
+		}
+		found[entry] = true
+		cols[entry] = i
+	}
+	for _, ok := range found {
+		if !ok {
+			return cols, false
+		}
+	}
+	return cols, true
+}
+
+// `Reorder` returns the parts of `record`, laid out according to `cols`, in
+// the positional order expected by `NewRoomFromRecord`.
+func (cols Columns) Reorder(record []string) ([]string, error) {
+	reordered := make([]string, numEntries)
+	for entry, col := range cols {
+		if col < 0 || col >= len(record) {
+			return nil, fmt.Errorf("invalid record: missing column %d", col)
+		}
+		reordered[entry] = record[col]
+	}
+	return reordered, nil
+}
+
 // `Number` is the ID/room number of a room.
 type Number uint
 
-// `Attribute` is a property that a room can have.
+// `Attribute` is a property that a room can have. The methods of `Room` match
+// attributes exactly, so attributes which should be treated as one must be
+// passed in the same canonical form (see `Normalizer`).
 type Attribute string
 
+// `Split` splits a keyed attribute of the form "key=value" into its key and
+// value. For a bare attribute, the key is the whole attribute, the value is
+// empty and the returned boolean is false.
+func (a Attribute) Split() (key, value string, keyed bool) {
+	if i := strings.IndexByte(string(a), '='); i >= 0 {
+		return string(a[:i]), string(a[i+1:]), true
+	}
+	return string(a), "", false
+}
+
+// `AttributeSet` is a set of attributes, supporting constant-time membership
+// checks. The zero value is not usable - use `NewAttributeSet`.
+type AttributeSet map[Attribute]struct{}
+
 // `State` indicates the current state of the `Room`.
 type State string
 
 // `Room` is a room in a hotel. It has an `ID` (the room number), a price, a
 // current state and a set of attributes.
 type Room struct {
-	mu    *sync.RWMutex
-	id    Number
-	price uint
-	state State
-	attrs map[Attribute]struct{}
+	// changes counts the changes made to the room (see `Changes`). It is
+	// accessed atomically, and is the first field so that it is 64-bit aligned
+	// on 32-bit platforms.
+	changes uint64
+	mu      *sync.RWMutex
+	id      Number
+	price   uint
+	state   State
+	attrs   map[Attribute]struct{}
+	// priorState is the state of the room before it was deactivated
+	priorState State
+	// priceHistory records the changes made to the price, oldest first
+	priceHistory []PriceChange
+	// deleted marks the room as (soft) deleted
+	deleted bool
+	// notes are free-form annotations made by staff
+	notes string
+	// modified is the date of the last change to the room (nil if unchanged
+	// since creation)
+	modified *date.Date
+}
+
+// `PriceChange` records a change of the price of a `Room`.
+type PriceChange struct {
+	Date     date.Date `json:"date"`
+	OldPrice uint      `json:"old_price"`
+	NewPrice uint      `json:"new_price"`
 }
 
 // `NewRoom` returns a pointer to a `Room` with the given `id` (room number).
@@ -50,62 +174,297 @@ func NewRoom(id Number) *Room {
 	}
 }
 
+// `NewValidRoom` returns a pointer to a `Room` with the given `id`, `price`,
+// `state` and attributes `attrs`. Unlike `NewRoom`, the room is validated: an
+// error is returned if the price is zero or the state is not recognized.
+func NewValidRoom(id Number, price uint, state State, attrs []Attribute) (*Room, error) {
+	if price == 0 {
+		return nil, fmt.Errorf("invalid room (id: %d): price must be positive", id)
+	}
+	if !IsValidState(state) {
+		return nil, fmt.Errorf("invalid room (id: %d): unrecognized state '%s'", id, state)
+	}
+	roomAttrs := make(map[Attribute]struct{})
+	for _, attr := range attrs {
+		roomAttrs[attr] = struct{}{}
+	}
+	return &Room{
+		mu:    &sync.RWMutex{},
+		id:    id,
+		price: price,
+		state: state,
+		attrs: roomAttrs,
+	}, nil
+}
+
+// `IsValidState` returns whether `s` is one of the recognized room states.
+func IsValidState(s State) bool {
+	switch s {
+	case StateOccupied, StateUnavailable, StateFree:
+		return true
+	default:
+		return false
+	}
+}
+
+// `Normalizer` maps an attribute to its canonical form, so that attributes
+// which differ only superficially (e.g. in case) are treated as one.
+type Normalizer func(Attribute) Attribute
+
+// `LowerCase` is a `Normalizer` which lower-cases attributes. It is the default
+// normalizer.
+func LowerCase(a Attribute) Attribute {
+	return Attribute(strings.ToLower(string(a)))
+}
+
+// `Verbatim` is a `Normalizer` which leaves attributes unchanged.
+func Verbatim(a Attribute) Attribute {
+	return a
+}
+
+// `NewRoomFromRecord` returns a pointer to the `Room` described by `record`,
+// whose parts are in positional order (see `EntryID` etc.). Whitespace around
+// each part, and around each of the comma separated attributes, is ignored, as
+// are empty attributes. Attributes are normalized with `LowerCase`.
 func NewRoomFromRecord(record []string, validAttributes []Attribute) (*Room, error) {
+	return NewRoomFromRecordWith(record, validAttributes, LowerCase)
+}
+
+// `NewRoomFromRecordWith` is like `NewRoomFromRecord`, but the attributes are
+// normalized with `normalize`.
+func NewRoomFromRecordWith(record []string, validAttributes []Attribute, normalize Normalizer) (*Room, error) {
 	const recordLen = 4
 	if len(record) != recordLen {
 		return nil, fmt.Errorf("invalid record: expected %d entries", recordLen)
 	}
-	id64, err := strconv.ParseUint(record[EntryID], 10, 64)
+	idStr := strings.TrimSpace(record[EntryID])
+	id, err := strconv.ParseUint(idStr, 10, bits.UintSize)
 	if err != nil {
-		return nil, fmt.Errorf("invalid record (id: '%s'): %s", record[0], err.Error())
+		return nil, fmt.Errorf("invalid record (id: '%s'): %s", idStr, err.Error())
 	}
-	price64, err := strconv.ParseUint(record[EntryPrice], 10, 64)
+	priceStr := strings.TrimSpace(record[EntryPrice])
+	price, err := strconv.ParseUint(priceStr, 10, bits.UintSize)
 	if err != nil {
-		return nil, fmt.Errorf("invalid record (price: '%s'): %s", record[1], err.Error())
-	}
-	var state State
-	stateStr := record[EntryState]
-	switch stateStr {
-	case "OCCUPIED":
-		state = StateOccupied
-	case "UNAVAILABLE":
-		state = StateUnavailable
-	case "FREE":
-		state = StateFree
-	default:
-		return nil, fmt.Errorf("invalid record (state: '%s;): unrecognized state", stateStr)
+		return nil, fmt.Errorf("invalid record (price: '%s'): %s", priceStr, err.Error())
+	}
+	state := State(strings.TrimSpace(record[EntryState]))
+	if !IsValidState(state) {
+		return nil, fmt.Errorf("invalid record (state: '%s'): unrecognized state", state)
 	}
 	roomAttrs := make(map[Attribute]struct{})
 	for _, attr := range strings.Split(record[EntryAttributes], ",") {
-		roomAttrs[Attribute(attr)] = struct{}{}
+		if attr = strings.TrimSpace(attr); attr != "" {
+			roomAttrs[normalize(Attribute(attr))] = struct{}{}
+		}
 	}
 	room := &Room{
 		mu:    &sync.RWMutex{},
-		id:    Number(uint(id64)),
-		price: uint(price64),
+		id:    Number(id),
+		price: uint(price),
 		state: state,
 		attrs: roomAttrs,
 	}
 	return room, nil
 }
 
+// `ToRecord` returns the record describing the room, in positional order (see
+// `EntryID` etc.), which is the canonical serialization of a room: the
+// attributes are comma separated, in ascending order. `NewRoomFromRecord`
+// parses the record back into an equal room, provided that no attribute
+// contains a comma, is empty, has surrounding whitespace or is changed by
+// normalization.
+func (r *Room) ToRecord() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	attrs := AttributeSet(r.attrs).Slice()
+	attrStrs := make([]string, len(attrs))
+	for i, attr := range attrs {
+		attrStrs[i] = string(attr)
+	}
+	record := make([]string, numEntries)
+	record[EntryID] = strconv.FormatUint(uint64(r.id), 10)
+	record[EntryPrice] = strconv.FormatUint(uint64(r.price), 10)
+	record[EntryState] = string(r.state)
+	record[EntryAttributes] = strings.Join(attrStrs, ",")
+	return record
+}
+
 // `ID` returns the ID (room number) of the room.
 func (r *Room) ID() Number {
 	// id is immutable - do not need to lock mutex for read (no writers exist)
 	return r.id
 }
 
+// `Price` returns the price of the room.
+func (r *Room) Price() uint {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.price
+}
+
+// `SetPrice` sets the price of the room to `price`. If the price changes, the
+// change is recorded (dated by `date.Today`) in the room's price history.
+func (r *Room) SetPrice(price uint) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.setPrice(price)
+}
+
+// `setPrice` implements `SetPrice`.
+//
+// The caller must hold the write lock of `r`.
+func (r *Room) setPrice(price uint) {
+	if price == r.price {
+		return
+	}
+	r.priceHistory = append(r.priceHistory, PriceChange{
+		Date:     *date.Today(),
+		OldPrice: r.price,
+		NewPrice: price,
+	})
+	r.price = price
+	r.touch()
+}
+
+// `PriceHistory` returns the changes made to the price of the room through
+// `SetPrice`, oldest first.
+func (r *Room) PriceHistory() []PriceChange {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	history := make([]PriceChange, len(r.priceHistory))
+	copy(history, r.priceHistory)
+	return history
+}
+
+// `SetState` sets the state of the room to `s`, returning an error if `s` is
+// not a recognized state. Any state remembered by `Deactivate` is forgotten.
+func (r *Room) SetState(s State) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.setState(s)
+}
+
+// `setState` implements `SetState`.
+//
+// The caller must hold the write lock of `r`.
+func (r *Room) setState(s State) error {
+	if !IsValidState(s) {
+		return fmt.Errorf("invalid state '%s'", s)
+	}
+	r.state = s
+	r.priorState = ""
+	r.touch()
+	return nil
+}
+
+// `State` returns the current state of the room.
+func (r *Room) State() State {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.state
+}
+
 // `AddAttribute` adds the given `RoomAttribute`, `attr`, to the room.
 func (r *Room) AddAttribute(attr Attribute) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	r.addAttribute(attr)
+}
+
+// `addAttribute` implements `AddAttribute`.
+//
+// The caller must hold the write lock of `r`.
+func (r *Room) addAttribute(attr Attribute) {
 	r.attrs[attr] = struct{}{}
+	r.touch()
+}
+
+// `NormalizeAttributes` replaces each attribute of the room with its canonical
+// form, as given by `normalize`. Since the attributes keep their meaning, the
+// room is not considered to have been modified.
+func (r *Room) NormalizeAttributes(normalize Normalizer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	attrs := make(map[Attribute]struct{}, len(r.attrs))
+	for attr := range r.attrs {
+		attrs[normalize(attr)] = struct{}{}
+	}
+	r.attrs = attrs
+	r.changed()
+}
+
+// `RemoveAttribute` removes the attribute `attr` from the room, returning
+// whether the room had it.
+func (r *Room) RemoveAttribute(attr Attribute) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.attrs[attr]; !ok {
+		return false
+	}
+	delete(r.attrs, attr)
+	r.touch()
+	return true
+}
+
+// `Attributes` returns the attributes of the room, in ascending order.
+func (r *Room) Attributes() []Attribute {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return AttributeSet(r.attrs).Slice()
+}
+
+// `AttributeCount` returns the number of attributes the room has.
+func (r *Room) AttributeCount() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.attrs)
+}
+
+// `HasAttribute` returns whether the room has the attribute `attr`.
+func (r *Room) HasAttribute(attr Attribute) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.attrs[attr]
+	return ok
+}
+
+// `AttributeValue` returns the value of the keyed attribute (an attribute of
+// the form "key=value") of the room with the given `key`. If the room has a
+// bare attribute equal to `key`, it is treated as a valueless flag and the
+// empty string is returned. The returned boolean is false if the room has no
+// such attribute.
+//
+// A keyed attribute takes precedence over a bare one, and if the room has
+// several values for `key`, the least (in ascending order) is returned, so
+// that the result does not depend on the order of the attributes.
+func (r *Room) AttributeValue(key string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var (
+		value       string
+		found, flag bool
+	)
+	for attr := range r.attrs {
+		k, v, keyed := attr.Split()
+		if k != key {
+			continue
+		}
+		if !keyed {
+			flag = true
+		} else if !found || v < value {
+			value, found = v, true
+		}
+	}
+	if found {
+		return value, true
+	}
+	return "", flag
 }
 
 // `Satisfies` returns whether the room satisfies the given attributes `attrs`.
 func (r *Room) Satisfies(attrs []Attribute) bool {
 	r.mu.RLock()
-	defer r.mu.RLocker()
+	defer r.mu.RUnlock()
 	for _, attr := range attrs {
 		if _, ok := r.attrs[attr]; !ok {
 			return false
@@ -113,3 +472,351 @@ func (r *Room) Satisfies(attrs []Attribute) bool {
 	}
 	return true
 }
+
+// `SatisfiesSet` returns whether the room has every attribute in `attrs`.
+func (r *Room) SatisfiesSet(attrs AttributeSet) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return attrs.Subset(AttributeSet(r.attrs))
+}
+
+// `RoundedPrice` returns the price of the room rounded to the nearest multiple
+// of `nearest`, with halves rounded up. If `nearest` is 0, the exact price is
+// returned. If rounding up would overflow a `uint`, the price is rounded down
+// instead.
+func (r *Room) RoundedPrice(nearest uint) uint {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if nearest == 0 {
+		return r.price
+	}
+	rem := r.price % nearest
+	down := r.price - rem
+	// rem >= nearest-rem is rem >= nearest/2 (rounding halves up), without
+	// overflowing
+	if rem == 0 || rem < nearest-rem || down > ^uint(0)-nearest {
+		return down
+	}
+	return down + nearest
+}
+
+// `Clone` returns a deep copy of the room, with its own mutex, which can be
+// modified without affecting `r`. The change counter of the copy starts from
+// zero (see `Changes`).
+func (r *Room) Clone() *Room {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	attrs := make(map[Attribute]struct{}, len(r.attrs))
+	for attr := range r.attrs {
+		attrs[attr] = struct{}{}
+	}
+	return &Room{
+		mu:    &sync.RWMutex{},
+		id:    r.id,
+		price: r.price,
+		state: r.state,
+		attrs: attrs,
+
+		priorState:   r.priorState,
+		priceHistory: append([]PriceChange(nil), r.priceHistory...),
+		deleted:      r.deleted,
+		notes:        r.notes,
+		modified:     r.modified,
+	}
+}
+
+// `Equal` returns whether the rooms `r` and `o` have the same ID, price, state
+// and set of attributes.
+func (r *Room) Equal(o *Room) bool {
+	if r == o {
+		return true
+	}
+	// compare against a copy of `o`, so that the locks of both rooms are never
+	// held together - otherwise concurrent calls with the arguments swapped
+	// could deadlock with waiting writers
+	o = o.Clone()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.id != o.id || r.price != o.price || r.state != o.state ||
+		len(r.attrs) != len(o.attrs) {
+		return false
+	}
+	for attr := range r.attrs {
+		if _, ok := o.attrs[attr]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// `NewAttributeSet` returns an `AttributeSet` containing the attributes
+// `attrs`.
+func NewAttributeSet(attrs ...Attribute) AttributeSet {
+	s := make(AttributeSet, len(attrs))
+	for _, attr := range attrs {
+		s.Add(attr)
+	}
+	return s
+}
+
+// `Add` adds the attribute `attr` to the set.
+func (s AttributeSet) Add(attr Attribute) {
+	s[attr] = struct{}{}
+}
+
+// `Has` returns whether the attribute `attr` is in the set.
+func (s AttributeSet) Has(attr Attribute) bool {
+	_, ok := s[attr]
+	return ok
+}
+
+// `Slice` returns the attributes in the set, in ascending order.
+func (s AttributeSet) Slice() []Attribute {
+	attrs := make([]Attribute, 0, len(s))
+	for attr := range s {
+		attrs = append(attrs, attr)
+	}
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i] < attrs[j] })
+	return attrs
+}
+
+// `Equal` returns whether the set contains exactly the attributes in `other`.
+func (s AttributeSet) Equal(other AttributeSet) bool {
+	return len(s) == len(other) && s.Subset(other)
+}
+
+// `Subset` returns whether every attribute in the set is also in `other`.
+func (s AttributeSet) Subset(other AttributeSet) bool {
+	if len(s) > len(other) {
+		return false
+	}
+	for attr := range s {
+		if !other.Has(attr) {
+			return false
+		}
+	}
+	return true
+}
+
+// `roomJSON` is the JSON representation of a `Room`.
+type roomJSON struct {
+	ID         Number      `json:"id"`
+	Price      uint        `json:"price"`
+	State      State       `json:"state"`
+	Attributes []Attribute `json:"attributes"`
+	Notes      string      `json:"notes,omitempty"`
+}
+
+// `MarshalJSON` returns the JSON encoding of the room. The attributes are
+// encoded as an array, in ascending order.
+func (r *Room) MarshalJSON() ([]byte, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return json.Marshal(roomJSON{
+		ID:         r.id,
+		Price:      r.price,
+		State:      r.state,
+		Attributes: AttributeSet(r.attrs).Slice(),
+		Notes:      r.notes,
+	})
+}
+
+// `UnmarshalJSON` sets the room to the one encoded in `data`, in the format
+// produced by `MarshalJSON`. An error is returned if the encoded state is not
+// recognized.
+func (r *Room) UnmarshalJSON(data []byte) error {
+	var rj roomJSON
+	if err := json.Unmarshal(data, &rj); err != nil {
+		return err
+	}
+	if !IsValidState(rj.State) {
+		return fmt.Errorf("invalid room (id: %d): unrecognized state '%s'", rj.ID, rj.State)
+	}
+	if r.mu == nil {
+		r.mu = &sync.RWMutex{}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.id = rj.ID
+	r.price = rj.Price
+	r.state = rj.State
+	r.notes = rj.Notes
+	r.attrs = make(map[Attribute]struct{}, len(rj.Attributes))
+	for _, attr := range rj.Attributes {
+		r.attrs[attr] = struct{}{}
+	}
+	r.changed()
+	return nil
+}
+
+// `Deactivate` makes the room unavailable (e.g. for maintenance), remembering
+// its current state so that it can be restored by `Reactivate`. Deactivating an
+// unavailable room has no effect.
+func (r *Room) Deactivate() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.state == StateUnavailable {
+		return
+	}
+	r.priorState = r.state
+	r.state = StateUnavailable
+	r.touch()
+}
+
+// `Reactivate` restores the state the room was in before it was deactivated by
+// `Deactivate`. It has no effect if the room is not deactivated.
+func (r *Room) Reactivate() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.state != StateUnavailable || r.priorState == "" {
+		return
+	}
+	r.state = r.priorState
+	r.priorState = ""
+	r.touch()
+}
+
+// `SatisfiesPattern` returns whether the room has, for every pattern in
+// `patterns`, an attribute matching it. A pattern ending in "*" matches any
+// attribute which starts with the rest of the pattern (e.g. "view_*" matches
+// "view_sea"). A pattern without a trailing wildcard must match an attribute
+// exactly.
+func (r *Room) SatisfiesPattern(patterns []string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, pattern := range patterns {
+		if !strings.HasSuffix(pattern, "*") {
+			if _, ok := r.attrs[Attribute(pattern)]; !ok {
+				return false
+			}
+			continue
+		}
+		prefix := strings.TrimSuffix(pattern, "*")
+		matched := false
+		for attr := range r.attrs {
+			if strings.HasPrefix(string(attr), prefix) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// `SoftDelete` marks the room as deleted. A deleted room keeps all of its data
+// (including its history) and can be recovered with `Restore`.
+func (r *Room) SoftDelete() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deleted = true
+	r.touch()
+}
+
+// `Restore` recovers a room deleted by `SoftDelete`.
+func (r *Room) Restore() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deleted = false
+	r.touch()
+}
+
+// `IsDeleted` returns whether the room has been deleted by `SoftDelete`.
+func (r *Room) IsDeleted() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.deleted
+}
+
+// `Notes` returns the notes made on the room.
+func (r *Room) Notes() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.notes
+}
+
+// `SetNotes` replaces the notes made on the room with `notes`. Notes are
+// included in the JSON encoding of the room, but not in its record.
+func (r *Room) SetNotes(notes string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notes = notes
+	r.touch()
+}
+
+// `touch` records that the room was changed today (as determined by
+// `date.Today`).
+//
+// The caller must hold the write lock of `r`.
+func (r *Room) touch() {
+	r.modified = date.Today()
+	r.changed()
+}
+
+// `changed` increments the change counter of the room (see `Changes`).
+//
+// The caller must hold the write lock of `r`.
+func (r *Room) changed() {
+	atomic.AddUint64(&r.changes, 1)
+}
+
+// `Changes` returns the number of changes made to the room since it was
+// created. The counter only ever increases, so a `Hotel` compares it to learn
+// of changes made to its rooms directly. It does not take the room's lock.
+func (r *Room) Changes() uint64 {
+	return atomic.LoadUint64(&r.changes)
+}
+
+// `Touch` records that the room was changed today, as every method which
+// modifies the room does.
+func (r *Room) Touch() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.touch()
+}
+
+// `Modified` returns the date on which the room was last changed, or nil if it
+// has not been changed since it was created.
+func (r *Room) Modified() *date.Date {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.modified == nil {
+		return nil
+	}
+	return r.modified.Clone()
+}
+
+// `RoomMutator` modifies a `Room` on behalf of `Room.Update`. It must not be
+// used after the function passed to `Update` returns.
+type RoomMutator struct {
+	r *Room
+}
+
+// `SetPrice` is like `Room.SetPrice`.
+func (m *RoomMutator) SetPrice(price uint) {
+	m.r.setPrice(price)
+}
+
+// `SetState` is like `Room.SetState`.
+func (m *RoomMutator) SetState(s State) error {
+	return m.r.setState(s)
+}
+
+// `AddAttribute` is like `Room.AddAttribute`.
+func (m *RoomMutator) AddAttribute(attr Attribute) {
+	m.r.addAttribute(attr)
+}
+
+// `Update` calls `fn` with a `RoomMutator` for the room, holding the room's
+// write lock throughout. This applies several changes (e.g. to the price and
+// the state) atomically: other goroutines observe either none or all of them.
+// Each change is counted (see `Changes`), as when made through the methods of
+// the room. `fn` must not call any other method of
+// the room, as it would deadlock.
+func (r *Room) Update(fn func(mut *RoomMutator)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fn(&RoomMutator{r: r})
+}
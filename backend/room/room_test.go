@@ -0,0 +1,605 @@
+package room
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/navaz-alani/hotel/date"
+)
+
+// `newTestRoom` returns a valid room, failing the test if it cannot be
+// created.
+func newTestRoom(t *testing.T, id Number, price uint, state State, attrs ...Attribute) *Room {
+	t.Helper()
+	r, err := NewValidRoom(id, price, state, attrs)
+	if err != nil {
+		t.Fatalf("creating room: %s", err.Error())
+	}
+	return r
+}
+
+func TestUpdate(t *testing.T) {
+	r := newTestRoom(t, 1, 100, StateFree)
+	changes := r.Changes()
+	var stateErr, badStateErr error
+	r.Update(func(mut *RoomMutator) {
+		mut.SetPrice(150)
+		stateErr = mut.SetState(StateOccupied)
+		badStateErr = mut.SetState("CLEANING")
+		mut.AddAttribute("balcony")
+	})
+	if stateErr != nil {
+		t.Errorf("SetState: unexpected error %s", stateErr.Error())
+	}
+	if badStateErr == nil {
+		t.Errorf("SetState: expected error for an invalid state")
+	}
+	if got := r.Price(); got != 150 {
+		t.Errorf("price: got %d, want 150", got)
+	}
+	if got := r.State(); got != StateOccupied {
+		t.Errorf("state: got %s, want %s", got, StateOccupied)
+	}
+	if !r.HasAttribute("balcony") {
+		t.Errorf("attribute not added")
+	}
+	if got := len(r.PriceHistory()); got != 1 {
+		t.Errorf("price history: got %d changes, want 1", got)
+	}
+	if r.Modified() == nil {
+		t.Errorf("room not marked as modified")
+	}
+	if r.Changes() <= changes {
+		t.Errorf("changes not counted")
+	}
+}
+
+func TestUpdateIsAtomic(t *testing.T) {
+	// each update moves the room between two consistent configurations, so a
+	// snapshot with the price of one and the state of the other would be a
+	// half-applied update
+	configs := []struct {
+		price uint
+		state State
+	}{
+		{100, StateFree},
+		{200, StateOccupied},
+	}
+	r := newTestRoom(t, 1, configs[0].price, configs[0].state)
+	const updates = 1000
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= updates; i++ {
+			c := configs[i%2]
+			r.Update(func(mut *RoomMutator) {
+				mut.SetPrice(c.price)
+				mut.SetState(c.state)
+			})
+		}
+	}()
+	for i := 0; i < updates; i++ {
+		// `Clone` reads the room under a single acquisition of its lock
+		snapshot := r.Clone()
+		price, state := snapshot.Price(), snapshot.State()
+		if (price == configs[0].price) != (state == configs[0].state) {
+			t.Fatalf("observed half-applied update: price %d, state %s", price, state)
+		}
+	}
+	wg.Wait()
+}
+
+func TestRecordRoundTrip(t *testing.T) {
+	maxUint := strconv.FormatUint(uint64(^uint(0)), 10)
+	tests := []struct {
+		name   string
+		record []string
+		// want is the canonical record of the parsed room
+		want []string
+	}{
+		{
+			"canonical",
+			[]string{"101", "100", "FREE", "balcony,sea_view"},
+			[]string{"101", "100", "FREE", "balcony,sea_view"},
+		},
+		{
+			"no attributes",
+			[]string{"102", "80", "OCCUPIED", ""},
+			[]string{"102", "80", "OCCUPIED", ""},
+		},
+		{
+			"empty attributes",
+			[]string{"103", "80", "FREE", ",balcony,,"},
+			[]string{"103", "80", "FREE", "balcony"},
+		},
+		{
+			"whitespace",
+			[]string{" 104 ", "\t90", "UNAVAILABLE ", " sea_view , balcony "},
+			[]string{"104", "90", "UNAVAILABLE", "balcony,sea_view"},
+		},
+		{
+			"unordered and differently cased attributes",
+			[]string{"105", "90", "FREE", "Sea_View,BALCONY,balcony"},
+			[]string{"105", "90", "FREE", "balcony,sea_view"},
+		},
+		{
+			"zero price",
+			[]string{"106", "0", "FREE", "balcony"},
+			[]string{"106", "0", "FREE", "balcony"},
+		},
+		{
+			"largest price and number",
+			[]string{maxUint, maxUint, "FREE", "view=sea"},
+			[]string{maxUint, maxUint, "FREE", "view=sea"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewRoomFromRecord(tt.record, nil)
+			if err != nil {
+				t.Fatalf("parsing %q: %s", tt.record, err.Error())
+			}
+			record := r.ToRecord()
+			if !reflect.DeepEqual(record, tt.want) {
+				t.Errorf("ToRecord: got %q, want %q", record, tt.want)
+			}
+			parsed, err := NewRoomFromRecord(record, nil)
+			if err != nil {
+				t.Fatalf("parsing %q: %s", record, err.Error())
+			}
+			if !parsed.Equal(r) {
+				t.Errorf("round trip of %q changed the room", record)
+			}
+		})
+	}
+}
+
+// `FuzzRecordRoundTrip` checks that every record which parses as a room
+// survives a round trip through its canonical record - run it with
+// `go test -fuzz FuzzRecordRoundTrip ./room`.
+func FuzzRecordRoundTrip(f *testing.F) {
+	maxUint := strconv.FormatUint(uint64(^uint(0)), 10)
+	seeds := [][4]string{
+		{"101", "100", "FREE", "balcony,sea_view"},
+		{"102", "80", "OCCUPIED", ""},
+		{"103", "80", "FREE", ",,"},
+		{" 104 ", "\t90", "UNAVAILABLE ", " sea_view , balcony "},
+		{"105", "0", "FREE", "balcony"},
+		{maxUint, maxUint, "FREE", "view=sea,beds=2,a b"},
+	}
+	for _, seed := range seeds {
+		f.Add(seed[0], seed[1], seed[2], seed[3])
+	}
+	f.Fuzz(func(t *testing.T, num, price, state, attrs string) {
+		r, err := NewRoomFromRecord([]string{num, price, state, attrs}, nil)
+		if err != nil {
+			return
+		}
+		record := r.ToRecord()
+		parsed, err := NewRoomFromRecord(record, nil)
+		if err != nil {
+			t.Fatalf("parsing %q: %s", record, err.Error())
+		}
+		if !parsed.Equal(r) {
+			t.Fatalf("round trip of %q changed the room", record)
+		}
+		if again := parsed.ToRecord(); !reflect.DeepEqual(again, record) {
+			t.Fatalf("canonical record %q became %q", record, again)
+		}
+	})
+}
+
+// `setToday` makes `date.Today` return the date `year-month-day`, returning a
+// function which restores the clock.
+func setToday(year, month, day int) func() {
+	prev := date.Now
+	date.Now = func() time.Time {
+		return time.Date(year, time.Month(month), day, 12, 0, 0, 0, time.UTC)
+	}
+	return func() { date.Now = prev }
+}
+
+func TestNewValidRoom(t *testing.T) {
+	tests := []struct {
+		name  string
+		price uint
+		state State
+		valid bool
+	}{
+		{"valid", 100, StateFree, true},
+		{"zero price", 0, StateFree, false},
+		{"unknown state", 100, "CLEANING", false},
+		{"empty state", 100, "", false},
+	}
+	for _, tt := range tests {
+		r, err := NewValidRoom(1, tt.price, tt.state, []Attribute{"balcony"})
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: got error %v, want valid: %t", tt.name, err, tt.valid)
+			continue
+		}
+		if tt.valid && (r.Price() != tt.price || r.State() != tt.state || !r.HasAttribute("balcony")) {
+			t.Errorf("%s: room not constructed as given", tt.name)
+		}
+	}
+}
+
+func TestNewRoomFromRecord(t *testing.T) {
+	tests := []struct {
+		name   string
+		record []string
+		attrs  []Attribute
+		valid  bool
+	}{
+		{"valid", []string{"1", "10", "FREE", "balcony"}, []Attribute{"balcony"}, true},
+		{"mixed case collapses", []string{"1", "10", "FREE", "Balcony,BALCONY,balcony"}, []Attribute{"balcony"}, true},
+		{"keyed and bare", []string{"1", "10", "FREE", "view=sea,beds=2,wifi"}, []Attribute{"beds=2", "view=sea", "wifi"}, true},
+		{"too few entries", []string{"1", "10", "FREE"}, nil, false},
+		{"too many entries", []string{"1", "10", "FREE", "a", "b"}, nil, false},
+		{"bad number", []string{"one", "10", "FREE", ""}, nil, false},
+		{"negative price", []string{"1", "-10", "FREE", ""}, nil, false},
+		{"bad state", []string{"1", "10", "free", ""}, nil, false},
+	}
+	for _, tt := range tests {
+		r, err := NewRoomFromRecord(tt.record, nil)
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: got error %v, want valid: %t", tt.name, err, tt.valid)
+			continue
+		}
+		if tt.valid && !reflect.DeepEqual(r.Attributes(), tt.attrs) {
+			t.Errorf("%s: got attributes %v, want %v", tt.name, r.Attributes(), tt.attrs)
+		}
+	}
+	r, err := NewRoomFromRecordWith([]string{"1", "10", "FREE", "Balcony,balcony"}, nil, Verbatim)
+	if err != nil {
+		t.Fatalf("NewRoomFromRecordWith: %s", err.Error())
+	}
+	if got := r.AttributeCount(); got != 2 {
+		t.Errorf("Verbatim: got %d attributes, want 2", got)
+	}
+}
+
+func TestColumnsFromHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []string
+		want   Columns
+		ok     bool
+	}{
+		{"default order", []string{"room_number", "price", "state", "attributes"}, DefaultColumns, true},
+		{"other order", []string{"state", "attributes", "price", "id"}, Columns{3, 2, 0, 1}, true},
+		{"padded and cased", []string{" Room_Number ", "PRICE ", "\tstate", "attributes "}, DefaultColumns, true},
+		{"extra column", []string{"notes", "room_number", "price", "state", "attributes"}, Columns{1, 2, 3, 4}, true},
+		{"missing column", []string{"room_number", "price", "state"}, Columns{}, false},
+		{"repeated column", []string{"room_number", "price", "state", "price", "attributes"}, Columns{}, false},
+		{"data", []string{"1", "10", "FREE", "balcony"}, Columns{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := ColumnsFromHeader(tt.header)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("%s: got %v, %t, want %v, %t", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+	cols := Columns{3, 2, 0, 1}
+	got, err := cols.Reorder([]string{"FREE", "balcony", "10", "7"})
+	if want := []string{"7", "10", "FREE", "balcony"}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Reorder: got %v (err: %v), want %v", got, err, want)
+	}
+	if _, err := cols.Reorder([]string{"FREE", "balcony"}); err == nil {
+		t.Errorf("Reorder: expected error for a short record")
+	}
+}
+
+func TestRoundedPrice(t *testing.T) {
+	const maxUint = ^uint(0)
+	tests := []struct {
+		name           string
+		price, nearest uint
+		want           uint
+	}{
+		{"up", 28, 5, 30},
+		{"down", 22, 5, 20},
+		{"half", 25, 10, 30},
+		{"just below half", 24, 10, 20},
+		{"exact", 40, 10, 40},
+		{"below half of nearest", 4, 10, 0},
+		{"zero nearest", 27, 0, 27},
+		{"largest price", maxUint, 10, maxUint - maxUint%10},
+		{"would overflow", maxUint - 1, 4, maxUint - maxUint%4},
+	}
+	for _, tt := range tests {
+		r := NewRoom(1)
+		r.SetPrice(tt.price)
+		if got := r.RoundedPrice(tt.nearest); got != tt.want {
+			t.Errorf("%s: RoundedPrice(%d) of %d: got %d, want %d", tt.name, tt.nearest, tt.price, got, tt.want)
+		}
+	}
+}
+
+func TestEqual(t *testing.T) {
+	base := newTestRoom(t, 1, 100, StateFree, "balcony", "sea_view")
+	tests := []struct {
+		name  string
+		other *Room
+		want  bool
+	}{
+		{"same", base, true},
+		{"equal", newTestRoom(t, 1, 100, StateFree, "sea_view", "balcony"), true},
+		{"different attributes", newTestRoom(t, 1, 100, StateFree, "balcony"), false},
+		{"different price", newTestRoom(t, 1, 120, StateFree, "balcony", "sea_view"), false},
+		{"different state", newTestRoom(t, 1, 100, StateOccupied, "balcony", "sea_view"), false},
+		{"different number", newTestRoom(t, 2, 100, StateFree, "balcony", "sea_view"), false},
+	}
+	for _, tt := range tests {
+		if got := base.Equal(tt.other); got != tt.want {
+			t.Errorf("%s: got %t, want %t", tt.name, got, tt.want)
+		}
+		if got := tt.other.Equal(base); got != tt.want {
+			t.Errorf("%s (swapped): got %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+	r := newTestRoom(t, 1, 100, StateFree, "balcony")
+	c := r.Clone()
+	c.SetPrice(200)
+	c.AddAttribute("minibar")
+	c.SetNotes("cloned")
+	if r.Price() != 100 || r.HasAttribute("minibar") || r.Notes() != "" {
+		t.Errorf("modifying the clone changed the original")
+	}
+}
+
+func TestSatisfiesReleasesLock(t *testing.T) {
+	r := newTestRoom(t, 1, 100, StateFree, "balcony")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.Satisfies([]Attribute{"balcony"})
+		r.SatisfiesSet(NewAttributeSet("balcony"))
+		// deadlocks if the read lock is still held
+		r.SetPrice(120)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("SetPrice blocked after Satisfies")
+	}
+	if got := r.Price(); got != 120 {
+		t.Errorf("price: got %d, want 120", got)
+	}
+}
+
+func TestAttributeSet(t *testing.T) {
+	small := NewAttributeSet("balcony")
+	large := NewAttributeSet("sea_view", "balcony", "minibar")
+	tests := []struct {
+		name string
+		a, b AttributeSet
+		want bool
+	}{
+		{"proper subset", small, large, true},
+		{"superset", large, small, false},
+		{"itself", large, large, true},
+		{"empty", NewAttributeSet(), small, true},
+		{"disjoint", NewAttributeSet("wifi"), large, false},
+	}
+	for _, tt := range tests {
+		if got := tt.a.Subset(tt.b); got != tt.want {
+			t.Errorf("%s: got %t, want %t", tt.name, got, tt.want)
+		}
+	}
+	if got, want := large.Slice(), []Attribute{"balcony", "minibar", "sea_view"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Slice: got %v, want %v", got, want)
+	}
+	if !large.Has("minibar") || large.Has("wifi") {
+		t.Errorf("Has: wrong membership")
+	}
+	if !large.Equal(NewAttributeSet("balcony", "minibar", "sea_view")) || large.Equal(small) {
+		t.Errorf("Equal: wrong result")
+	}
+	r := newTestRoom(t, 1, 100, StateFree, "balcony", "minibar")
+	if !r.SatisfiesSet(small) || r.SatisfiesSet(large) {
+		t.Errorf("SatisfiesSet: wrong result")
+	}
+}
+
+func TestAttributeValue(t *testing.T) {
+	r, err := NewRoomFromRecord([]string{"1", "10", "FREE", "view=sea,beds=2,wifi,pool,pool=indoor,tv=,floor=3,floor=1"}, nil)
+	if err != nil {
+		t.Fatalf("parsing record: %s", err.Error())
+	}
+	tests := []struct {
+		key   string
+		value string
+		ok    bool
+	}{
+		{"view", "sea", true},
+		{"beds", "2", true},
+		{"wifi", "", true},
+		{"tv", "", true},
+		// keyed attributes take precedence over bare ones
+		{"pool", "indoor", true},
+		// the least of several values
+		{"floor", "1", true},
+		{"sauna", "", false},
+		{"sea", "", false},
+	}
+	for _, tt := range tests {
+		// repeated, since the attributes are iterated in random order
+		for i := 0; i < 20; i++ {
+			value, ok := r.AttributeValue(tt.key)
+			if value != tt.value || ok != tt.ok {
+				t.Errorf("AttributeValue(%q): got %q, %t, want %q, %t", tt.key, value, ok, tt.value, tt.ok)
+				break
+			}
+		}
+	}
+}
+
+func TestDeactivate(t *testing.T) {
+	for _, state := range []State{StateFree, StateOccupied} {
+		r := newTestRoom(t, 1, 100, state)
+		r.Deactivate()
+		if got := r.State(); got != StateUnavailable {
+			t.Errorf("from %s: deactivated state %s", state, got)
+		}
+		r.Deactivate()
+		r.Reactivate()
+		if got := r.State(); got != state {
+			t.Errorf("from %s: reactivated state %s", state, got)
+		}
+	}
+	r := newTestRoom(t, 1, 100, StateUnavailable)
+	r.Reactivate()
+	if got := r.State(); got != StateUnavailable {
+		t.Errorf("reactivating a room which was not deactivated changed its state to %s", got)
+	}
+}
+
+func TestSetState(t *testing.T) {
+	r := newTestRoom(t, 1, 100, StateFree)
+	if err := r.SetState(StateOccupied); err != nil || r.State() != StateOccupied {
+		t.Errorf("SetState: got %s (err: %v)", r.State(), err)
+	}
+	if err := r.SetState("CLEANING"); err == nil || r.State() != StateOccupied {
+		t.Errorf("SetState: invalid state accepted")
+	}
+}
+
+func TestPriceHistory(t *testing.T) {
+	defer setToday(2021, 3, 1)()
+	r := newTestRoom(t, 1, 100, StateFree)
+	r.SetPrice(120)
+	r.SetPrice(120) // unchanged - not recorded
+	setToday(2021, 3, 2)
+	r.SetPrice(90)
+	want := []PriceChange{
+		{Date: date.Date{Day: 1, Month: 3, Year: 2021}, OldPrice: 100, NewPrice: 120},
+		{Date: date.Date{Day: 2, Month: 3, Year: 2021}, OldPrice: 120, NewPrice: 90},
+	}
+	if got := r.PriceHistory(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestSatisfiesPattern(t *testing.T) {
+	r := newTestRoom(t, 1, 100, StateFree, "view_sea", "balcony")
+	tests := []struct {
+		name     string
+		patterns []string
+		want     bool
+	}{
+		{"wildcard", []string{"view_*"}, true},
+		{"exact", []string{"balcony"}, true},
+		{"both", []string{"view_*", "balcony"}, true},
+		{"wildcard without match", []string{"bed_*"}, false},
+		{"exact is not a prefix", []string{"view_"}, false},
+		{"one pattern without match", []string{"view_*", "minibar"}, false},
+		{"none", nil, true},
+	}
+	for _, tt := range tests {
+		if got := r.SatisfiesPattern(tt.patterns); got != tt.want {
+			t.Errorf("%s: got %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSoftDelete(t *testing.T) {
+	r := newTestRoom(t, 1, 100, StateFree)
+	r.SoftDelete()
+	if !r.IsDeleted() {
+		t.Errorf("room not deleted")
+	}
+	r.Restore()
+	if r.IsDeleted() {
+		t.Errorf("room not restored")
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	r := newTestRoom(t, 7, 100, StateOccupied, "balcony", "view=sea")
+	r.SetNotes("AC serviced 2021-05")
+	if got := r.Notes(); got != "AC serviced 2021-05" {
+		t.Errorf("Notes: got %q", got)
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatalf("marshalling: %s", err.Error())
+	}
+	decoded := &Room{}
+	if err := json.Unmarshal(data, decoded); err != nil {
+		t.Fatalf("unmarshalling %s: %s", data, err.Error())
+	}
+	if !decoded.Equal(r) || decoded.Notes() != r.Notes() {
+		t.Errorf("round trip of %s changed the room", data)
+	}
+	// notes are not part of the record
+	if got := r.ToRecord(); len(got) != 4 || got[EntryAttributes] != "balcony,view=sea" {
+		t.Errorf("ToRecord: got %q", got)
+	}
+	if err := json.Unmarshal([]byte(`{"id": 1, "price": 1, "state": "CLEANING"}`), &Room{}); err == nil {
+		t.Errorf("unmarshalling an unrecognized state: expected error")
+	}
+}
+
+func TestAttributeCount(t *testing.T) {
+	if got := newTestRoom(t, 1, 100, StateFree, "a", "b", "c").AttributeCount(); got != 3 {
+		t.Errorf("got %d, want 3", got)
+	}
+	if got := newTestRoom(t, 1, 100, StateFree).AttributeCount(); got != 0 {
+		t.Errorf("got %d, want 0", got)
+	}
+}
+
+func TestModified(t *testing.T) {
+	defer setToday(2021, 3, 1)()
+	mutations := []struct {
+		name   string
+		mutate func(r *Room)
+	}{
+		{"SetPrice", func(r *Room) { r.SetPrice(150) }},
+		{"SetState", func(r *Room) { r.SetState(StateOccupied) }},
+		{"AddAttribute", func(r *Room) { r.AddAttribute("minibar") }},
+		{"RemoveAttribute", func(r *Room) { r.RemoveAttribute("balcony") }},
+		{"Deactivate", func(r *Room) { r.Deactivate() }},
+		{"SetNotes", func(r *Room) { r.SetNotes("note") }},
+		{"SoftDelete", func(r *Room) { r.SoftDelete() }},
+		{"Touch", func(r *Room) { r.Touch() }},
+	}
+	for _, m := range mutations {
+		r := newTestRoom(t, 1, 100, StateFree, "balcony")
+		// reads do not modify the room
+		r.Price()
+		r.State()
+		r.Attributes()
+		r.ToRecord()
+		if got := r.Modified(); got != nil {
+			t.Errorf("%s: modified (%v) before any change", m.name, got)
+		}
+		changes := r.Changes()
+		m.mutate(r)
+		if got := r.Modified(); got == nil || *got != (date.Date{Day: 1, Month: 3, Year: 2021}) {
+			t.Errorf("%s: got modified date %v", m.name, got)
+		}
+		if r.Changes() <= changes {
+			t.Errorf("%s: change not counted", m.name)
+		}
+	}
+}
+
+func TestNormalizeAttributes(t *testing.T) {
+	r := newTestRoom(t, 1, 100, StateFree, "Balcony", "balcony", "Sea_View")
+	r.NormalizeAttributes(LowerCase)
+	if got, want := r.Attributes(), []Attribute{"balcony", "sea_view"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if r.Modified() != nil {
+		t.Errorf("normalization marked the room as modified")
+	}
+}